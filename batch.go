@@ -0,0 +1,82 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+// BatchCodecRequest is implemented by a CodecRequest that may carry a batch
+// of requests, such as a JSON-RPC 2.0 batch.
+type BatchCodecRequest interface {
+	CodecRequest
+	// Returns the requests of the batch, or false if the request is not a
+	// batch and must be served as a single request.
+	Batch() ([]CodecRequest, bool)
+	// Writes the responses of the batch requests, in the order of the
+	// requests. A response is empty if the request had no response.
+	WriteBatchResponse(w http.ResponseWriter, responses [][]byte)
+}
+
+// serveBatch serves the requests of a batch, then writes their responses.
+func (s *Server) serveBatch(w http.ResponseWriter, r *http.Request, codec Codec, contentType string, batchReq BatchCodecRequest, requests []CodecRequest) {
+	buffers := make([]*responseBuffer, len(requests))
+	concurrency := s.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, codecReq := range requests {
+		buffers[i] = newResponseBuffer()
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(buffer *responseBuffer, codecReq CodecRequest) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			defer s.recoverBatchRequest(buffer, r, codecReq)
+			s.serveRequest(buffer, r, codec, contentType, codecReq)
+		}(buffers[i], codecReq)
+	}
+	wg.Wait()
+
+	responses := make([][]byte, len(buffers))
+	for i, buffer := range buffers {
+		responses[i] = buffer.body.Bytes()
+		// Keep the headers of the responses, e.g. deprecation warnings.
+		for key, values := range buffer.header {
+			if _, ok := w.Header()[key]; !ok {
+				w.Header()[key] = values
+			}
+		}
+	}
+	w.Header().Set("x-content-type-options", "nosniff")
+	batchReq.WriteBatchResponse(w, responses)
+}
+
+// recoverBatchRequest recovers a panic serving a request of a batch, e.g. in
+// the codec, and answers with an E_INTERNAL error through the codec request,
+// so that the response of the batch stays valid. It must be deferred.
+func (s *Server) recoverBatchRequest(buffer *responseBuffer, r *http.Request, codecReq CodecRequest) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	err, data := s.panicError(&CallInfo{Request: r}, v, debug.Stack())
+	buffer.reset()
+	defer func() {
+		// The codec request is broken: the request has no response.
+		if v := recover(); v != nil {
+			s.logger.Error("panic", "remote", r.RemoteAddr, "panic", v)
+			buffer.reset()
+		}
+	}()
+	codecReq.WriteErrorResponse(buffer, E_INTERNAL, err, data)
+}
@@ -0,0 +1,133 @@
+package bsonrpc
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Limard/rpcHttp"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrResponseError = errors.New("response error")
+
+type Service1Request struct {
+	A int
+	B int
+}
+
+type Service1Response struct {
+	Result int
+}
+
+type Service1 struct {
+}
+
+func (t *Service1) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A * req.B
+	return nil
+}
+
+func (t *Service1) ResponseError(r *http.Request, req *Service1Request, res *Service1Response) error {
+	return ErrResponseError
+}
+
+func (t *Service1) Greet(name string, times int, res *string) error {
+	*res = strings.Repeat("hello "+name+" ", times)
+	return nil
+}
+
+func TestService(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	s.RegisterService(new(Service1), "")
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var res Service1Response
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d, %v", res.Result, err)
+	}
+	err := Call(server.URL, "Service1.ResponseError", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_SERVER || replyErr.Message != ErrResponseError.Error() {
+		t.Errorf("Expected error %d %q, got %v", E_SERVER, ErrResponseError, err)
+	}
+}
+
+func TestMethodParams(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	if err := s.RegisterServiceWithOptions(new(Service1), "", rpcHttp.WithMethodParams("Greet", "name", "times")); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	params := struct {
+		Times int    `bson:"times"`
+		Name  string `bson:"name"`
+	}{2, "go"}
+	var res string
+	if err := Call(server.URL, "Service1.Greet", &params, &res); err != nil || res != "hello go hello go " {
+		t.Errorf("Wrong by-name response: %q, %v", res, err)
+	}
+}
+
+type benchItem struct {
+	ID   int64
+	Name string
+	Data []byte
+}
+
+type benchArgs struct {
+	Items []benchItem
+}
+
+var benchSizes = []int{1, 100, 10000}
+
+func newBenchArgs(n int) *benchArgs {
+	args := &benchArgs{Items: make([]benchItem, n)}
+	for i := range args.Items {
+		args.Items[i] = benchItem{ID: int64(i) << 40, Name: "item", Data: []byte("0123456789abcdef")}
+	}
+	return args
+}
+
+func BenchmarkReadRequest(b *testing.B) {
+	codec := NewCodec()
+	for _, n := range benchSizes {
+		body, _ := encodeClientRequest("Bench.Read", newBenchArgs(n))
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				r, _ := http.NewRequest("POST", "/", bytes.NewReader(body))
+				var args benchArgs
+				if err := codec.NewRequest(r).ReadRequest(&args); err != nil || len(args.Items) != n {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeClientResponse(b *testing.B) {
+	for _, n := range benchSizes {
+		body, _ := bson.Marshal(&serverResponse{Version: "1.0", Result: newBenchArgs(n), Id: 1})
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				var reply benchArgs
+				if err := decodeClientResponse(bytes.NewReader(body), &reply); err != nil || len(reply.Items) != n {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
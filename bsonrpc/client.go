@@ -2,11 +2,15 @@ package bsonrpc
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
+
+	"github.com/Limard/rpcHttp"
+
 	"gopkg.in/mgo.v2/bson"
-	"io/ioutil"
 )
 
 var ContentType = `application/bson`
@@ -23,8 +27,46 @@ type clientRequest struct {
 type clientResponse struct {
 	Id      interface{} `bson:"id"`
 	Version string      `bson:"msgpackrpc"`
-	Result  interface{} `bson:"result"`
-	Error   interface{} `bson:"error"`
+	Result  bson.Raw    `bson:"result"`
+	Error   bson.Raw    `bson:"error"`
+}
+
+// Client calls methods on BSON-RPC servers. It is safe for concurrent use.
+// Call and CallContext use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	logger     rpcHttp.Logger
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithClientLogger sets the logger of the client.
+func WithClientLogger(logger rpcHttp.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
 }
 
 func encodeClientRequest(method string, args interface{}) ([]byte, error) {
@@ -49,27 +91,25 @@ func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
 	}
 
 	// Error
-	if c.Error != nil {
+	if !isNull(c.Error) {
 		replyError := &Error{}
-		tempBuf, _ := bson.Marshal(c.Error)
-		if err := bson.Unmarshal(tempBuf, replyError); err != nil {
+		if err := c.Error.Unmarshal(replyError); err != nil {
 			return &Error{
 				Code:    E_PARSE,
-				Message: string(tempBuf),
+				Message: err.Error(),
 			}
 		}
 		return replyError
 	}
 
 	// Result
-	if c.Result == nil {
+	if isNull(c.Result) {
 		return &Error{
 			Code:    E_BAD_PARAMS,
 			Message: "result is null",
 		}
 	}
-	tempBuf, _ := bson.Marshal(c.Result)
-	if err := bson.Unmarshal(tempBuf, reply); err != nil {
+	if err := c.Result.Unmarshal(reply); err != nil {
 		return &Error{
 			Code:    E_PARSE,
 			Message: err.Error(),
@@ -80,23 +120,58 @@ func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
 }
 
 func Call(url string, method string, request interface{}, reply interface{}) (e error) {
-	jsonReqBuf, err := encodeClientRequest(method, request)
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+// CallContext calls method with client. The deadline of ctx, if any, is sent
+// to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (c *Client) Call(url string, method string, request interface{}, reply interface{}) error {
+	return c.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (c *Client) CallContext(ctx context.Context, url string, method string, request interface{}, reply interface{}) error {
+	fields := []interface{}{"method", method, "codec", ContentType, "remote", url}
+	reqBuf, err := encodeClientRequest(method, request)
+	if err != nil {
+		c.logger.Error("encodeClientRequest", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBuf))
 	if err != nil {
 		return &Error{
 			Code:    E_INVALID_REQ,
 			Message: err.Error()}
 	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
 
-	jsonReqBufR := bytes.NewReader(jsonReqBuf)
-	rsp, err := http.Post(url, ContentType, jsonReqBufR)
+	rsp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.logger.Error("http.Post", append(fields, "err", err)...)
 		return &Error{
 			Code:    E_SERVER,
 			Message: err.Error()}
 	}
 	defer rsp.Body.Close()
 
-	return decodeClientResponse(rsp.Body, reply)
+	err = decodeClientResponse(rsp.Body, reply)
+	if err != nil {
+		// The errors of bson are returned as is.
+		if replyErr, ok := err.(*Error); !ok || replyErr.Code == E_PARSE {
+			c.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+		}
+	}
+	return err
 }
 
 func ConvertError(err error) (replyError *Error) {
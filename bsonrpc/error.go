@@ -9,6 +9,8 @@ const (
 	E_BAD_PARAMS  = -32602
 	E_INTERNAL    = -32603
 	E_SERVER      = -32000
+	E_TIMEOUT     = -32001
+	E_BUSY        = -32002
 )
 
 type Error struct {
@@ -20,4 +22,4 @@ type Error struct {
 func (e *Error) Error() string {
 	b, _ := json.Marshal(e)
 	return string(b)
-}
\ No newline at end of file
+}
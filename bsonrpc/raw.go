@@ -0,0 +1,14 @@
+package bsonrpc
+
+import "gopkg.in/mgo.v2/bson"
+
+// BSON element kinds.
+const (
+	kindArray byte = 0x04
+	kindNull  byte = 0x0A
+)
+
+// isNull reports whether the raw BSON value is missing or null.
+func isNull(raw bson.Raw) bool {
+	return raw.Kind == 0 || raw.Kind == kindNull
+}
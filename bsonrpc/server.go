@@ -2,7 +2,6 @@ package bsonrpc
 
 import (
 	"io/ioutil"
-	"log"
 	"net/http"
 
 	"github.com/Limard/rpcHttp"
@@ -10,19 +9,36 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
-func NewCodec() *Codec {
-	return &Codec{encSel: rpcHttp.DefaultEncoderSelector}
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{encSel: rpcHttp.DefaultEncoderSelector, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithLogger sets the logger of the codec.
+func WithLogger(logger rpcHttp.Logger) CodecOption {
+	return func(c *Codec) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
 }
 
 // Codec creates a CodecRequest to process each request.
 type Codec struct {
 	encSel rpcHttp.EncoderSelector
+	logger rpcHttp.Logger
 }
 
 type serverRequest struct {
 	Version string      `bson:"msgpackrpc"`
 	Method  string      `bson:"method"`
-	Params  interface{} `bson:"params"`
+	Params  bson.Raw    `bson:"params"`
 	Id      interface{} `bson:"id"`
 }
 
@@ -53,13 +69,21 @@ func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
 		}
 	}
 	r.Body.Close()
-	return &CodecRequest{request: req, err: err, encoder: c.encSel.Select(r)}
+	return &CodecRequest{
+		request: req,
+		err:     err,
+		encoder: c.encSel.Select(r),
+		logger:  c.logger,
+		remote:  r.RemoteAddr,
+	}
 }
 
 type CodecRequest struct {
 	request *serverRequest
 	err     error
 	encoder rpcHttp.Encoder
+	logger  rpcHttp.Logger
+	remote  string
 }
 
 func (c *CodecRequest) Method() (string, error) {
@@ -69,13 +93,22 @@ func (c *CodecRequest) Method() (string, error) {
 	return "", c.err
 }
 
+// RequestID returns the id of the current request.
+func (c *CodecRequest) RequestID() interface{} {
+	return c.request.Id
+}
+
+// ReadRequest decodes the params into args. A params array holding a single
+// value is read as the whole args.
 func (c *CodecRequest) ReadRequest(args interface{}) error {
-	if c.err == nil && c.request.Params != nil {
-		tempBuf, _ := bson.Marshal(c.request.Params)
-		if err := bson.Unmarshal(tempBuf, args); err != nil {
-			params := [1]interface{}{args}
-			if err = bson.Unmarshal(tempBuf, &params); err != nil {
-				log.Printf("ERROR: %s", string(tempBuf))
+	if c.err == nil && !isNull(c.request.Params) {
+		if err := c.request.Params.Unmarshal(args); err != nil {
+			var params []bson.Raw
+			if c.request.Params.Kind == kindArray && c.request.Params.Unmarshal(&params) == nil && len(params) == 1 {
+				err = params[0].Unmarshal(args)
+			}
+			if err != nil {
+				c.logger.Warn("invalid params", c.logFields("err", err)...)
 				c.err = &Error{
 					Code:    E_INVALID_REQ,
 					Message: err.Error(),
@@ -120,16 +153,21 @@ func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, res *serverRes
 		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
 		w.Header().Set("Content-Type", "application/bson; charset=utf-8")
 
-		var buffer []byte
-		var err error
-		//buffer, err = bson.Marshal(c.request)
-
-		buffer, err = bson.Marshal(res)
-		w.Write(buffer)
-
+		buffer, err := bson.Marshal(res)
 		if err != nil {
-			log.Println("bson Encode:", err.Error())
+			c.logger.Error("bson Encode", c.logFields("err", err)...)
 			rpcHttp.WriteError(w, 400, err.Error())
+			return
+		}
+		if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+			c.logger.Warn("write response", c.logFields("err", err)...)
 		}
 	}
 }
+
+// logFields returns the log fields identifying the request followed by
+// keyvals.
+func (c *CodecRequest) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"method", c.request.Method, "codec", ContentType, "id", c.request.Id, "remote", c.remote}
+	return append(fields, keyvals...)
+}
@@ -0,0 +1,836 @@
+package cborrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CBOR major types, RFC 8949 section 3.1.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const (
+	tagDateTime  = 0 // RFC 3339 text
+	tagEpochTime = 1 // seconds since the epoch
+
+	simpleFalse     = 0xf4
+	simpleTrue      = 0xf5
+	simpleNull      = 0xf6
+	simpleUndefined = 0xf7
+
+	infoIndefinite = 31
+	breakCode      = 0xff
+
+	// maxDepth is the maximum nesting of arrays, maps and tags decoded.
+	maxDepth = 1000
+)
+
+var (
+	typeOfTime = reflect.TypeOf(time.Time{})
+	typeOfRaw  = reflect.TypeOf(RawMessage(nil))
+)
+
+// RawMessage is a raw encoded CBOR data item. It holds the params and the
+// results of the messages until they are decoded into their target type.
+type RawMessage []byte
+
+// ----------------------------------------------------------------------------
+// Encoding
+// ----------------------------------------------------------------------------
+
+// Marshal returns the CBOR encoding of v.
+//
+// Structs are encoded as maps keyed by the field names, or by the name in
+// the "cbor" tag of the field, as in `cbor:"name,omitempty"`; the tag "-"
+// ignores the field. []byte is encoded as a byte string, time.Time as an
+// epoch-based date/time with tag 1, nil pointers, interfaces, slices and
+// maps as null.
+func Marshal(v interface{}) ([]byte, error) {
+	var e encodeState
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+type encodeState struct {
+	bytes.Buffer
+}
+
+// head writes the initial byte of a data item of type major with argument n,
+// in its shortest form.
+func (e *encodeState) head(major byte, n uint64) {
+	var b [9]byte
+	b[0] = major << 5
+	switch {
+	case n < 24:
+		b[0] |= byte(n)
+		e.Write(b[:1])
+	case n <= math.MaxUint8:
+		b[0] |= 24
+		b[1] = byte(n)
+		e.Write(b[:2])
+	case n <= math.MaxUint16:
+		b[0] |= 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		e.Write(b[:3])
+	case n <= math.MaxUint32:
+		b[0] |= 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		e.Write(b[:5])
+	default:
+		b[0] |= 27
+		binary.BigEndian.PutUint64(b[1:], n)
+		e.Write(b[:9])
+	}
+}
+
+func (e *encodeState) encodeInt(n int64) {
+	if n >= 0 {
+		e.head(majorUint, uint64(n))
+	} else {
+		e.head(majorNegInt, uint64(-1-n))
+	}
+}
+
+func (e *encodeState) encodeFloat64(f float64) {
+	var b [9]byte
+	b[0] = majorSimple<<5 | 27
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	e.Write(b[:])
+}
+
+func (e *encodeState) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.WriteByte(simpleNull)
+		return nil
+	}
+	switch v.Type() {
+	case typeOfTime:
+		t := v.Interface().(time.Time)
+		e.head(majorTag, tagEpochTime)
+		if t.Nanosecond() == 0 {
+			e.encodeInt(t.Unix())
+		} else {
+			e.encodeFloat64(float64(t.Unix()) + float64(t.Nanosecond())/1e9)
+		}
+		return nil
+	case typeOfRaw:
+		if v.Len() == 0 {
+			e.WriteByte(simpleNull)
+		} else {
+			e.Write(v.Bytes())
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.WriteByte(simpleNull)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.WriteByte(simpleTrue)
+		} else {
+			e.WriteByte(simpleFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(majorUint, v.Uint())
+	case reflect.Float32:
+		var b [5]byte
+		b[0] = majorSimple<<5 | 26
+		binary.BigEndian.PutUint32(b[1:], math.Float32bits(float32(v.Float())))
+		e.Write(b[:])
+	case reflect.Float64:
+		e.encodeFloat64(v.Float())
+	case reflect.String:
+		e.head(majorText, uint64(v.Len()))
+		e.WriteString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.WriteByte(simpleNull)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			e.head(majorBytes, uint64(len(b)))
+			e.Write(b)
+			return nil
+		}
+		e.head(majorArray, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.WriteByte(simpleNull)
+			return nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		fields := structFields(v.Type())
+		n := 0
+		for _, f := range fields {
+			if !f.omitEmpty || !isEmptyValue(v.Field(f.index)) {
+				n++
+			}
+		}
+		e.head(majorMap, uint64(n))
+		for _, f := range fields {
+			field := v.Field(f.index)
+			if f.omitEmpty && isEmptyValue(field) {
+				continue
+			}
+			e.head(majorText, uint64(len(f.name)))
+			e.WriteString(f.name)
+			if err := e.encode(field); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// encodeMap writes the map v with its keys sorted by their encoding, as in
+// the core deterministic encoding of RFC 8949 section 4.2.1.
+func (e *encodeState) encodeMap(v reflect.Value) error {
+	type entry struct {
+		key   []byte
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		var key encodeState
+		if err := key.encode(iter.Key()); err != nil {
+			return err
+		}
+		entries = append(entries, entry{key.Bytes(), iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	e.head(majorMap, uint64(len(entries)))
+	for _, entry := range entries {
+		e.Write(entry.key)
+		if err := e.encode(entry.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field is a struct field encoded as a map entry.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// structFields returns the fields of struct type t encoded as map entries:
+// the exported fields, named by their cbor tag if any. A field with the tag
+// "-" is ignored.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("cbor")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{name: name, index: i, omitEmpty: opts == "omitempty"})
+	}
+	return fields
+}
+
+// fieldByName returns the field of the map key name, preferring an exact
+// match to a case-insensitive one.
+func fieldByName(fields []field, name string) (field, bool) {
+	var fold *field
+	for i := range fields {
+		if fields[i].name == name {
+			return fields[i], true
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, name) {
+			fold = &fields[i]
+		}
+	}
+	if fold != nil {
+		return *fold, true
+	}
+	return field{}, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ----------------------------------------------------------------------------
+// Decoding
+// ----------------------------------------------------------------------------
+
+// Unmarshal decodes the CBOR data item data into the value pointed to by v.
+//
+// Maps are decoded into structs by field name, or into maps; arrays into
+// slices, arrays, or the fields of a struct in order. Tag 1 and tag 0 are
+// decoded into time.Time, other tags are ignored. In an empty interface,
+// Unmarshal stores int64 or uint64, float64, bool, string, []byte,
+// time.Time, []interface{}, map[string]interface{} for maps with text keys
+// or map[interface{}]interface{}, and nil for null and undefined.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cbor: Unmarshal(non-pointer %T)", v)
+	}
+	d := &decodeState{data: data}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if d.off != len(d.data) {
+		return errors.New("cbor: trailing data after data item")
+	}
+	return nil
+}
+
+type decodeState struct {
+	data  []byte
+	off   int
+	depth int
+}
+
+// readHead reads the initial byte of a data item and its argument. The info
+// of an indefinite-length item is infoIndefinite.
+func (d *decodeState) readHead() (major byte, info byte, n uint64, err error) {
+	if d.off >= len(d.data) {
+		return 0, 0, 0, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.off]
+	d.off++
+	major, info = b>>5, b&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(d.data)-d.off < size {
+			return 0, 0, 0, io.ErrUnexpectedEOF
+		}
+		p := d.data[d.off : d.off+size]
+		d.off += size
+		switch size {
+		case 1:
+			n = uint64(p[0])
+		case 2:
+			n = uint64(binary.BigEndian.Uint16(p))
+		case 4:
+			n = uint64(binary.BigEndian.Uint32(p))
+		default:
+			n = binary.BigEndian.Uint64(p)
+		}
+		return major, info, n, nil
+	case info == infoIndefinite && major >= majorBytes && major <= majorMap:
+		return major, info, 0, nil
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: malformed initial byte 0x%02x", b)
+}
+
+// readString reads the content of a byte or text string. The bytes of a
+// definite-length string are not copied.
+func (d *decodeState) readString(major, info byte, n uint64) ([]byte, error) {
+	if info != infoIndefinite {
+		if n > uint64(len(d.data)-d.off) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := d.data[d.off : d.off+int(n)]
+		d.off += int(n)
+		return b, nil
+	}
+	// Indefinite-length string, the concatenation of definite-length chunks.
+	var b []byte
+	for {
+		if d.off >= len(d.data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if d.data[d.off] == breakCode {
+			d.off++
+			return b, nil
+		}
+		chunkMajor, chunkInfo, chunkLen, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == infoIndefinite {
+			return nil, errors.New("cbor: invalid indefinite-length string chunk")
+		}
+		chunk, err := d.readString(major, chunkInfo, chunkLen)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+// items calls f for each of the n items of an array, or the n pairs of a
+// map, until the break code if the length is indefinite.
+func (d *decodeState) items(info byte, n uint64, f func() error) error {
+	if d.depth++; d.depth > maxDepth {
+		return errors.New("cbor: exceeded max depth")
+	}
+	defer func() { d.depth-- }()
+	for i := uint64(0); info == infoIndefinite || i < n; i++ {
+		if info == infoIndefinite {
+			if d.off >= len(d.data) {
+				return io.ErrUnexpectedEOF
+			}
+			if d.data[d.off] == breakCode {
+				d.off++
+				return nil
+			}
+		}
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a data item.
+func (d *decodeState) skip() error {
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case majorBytes, majorText:
+		_, err = d.readString(major, info, n)
+	case majorArray:
+		err = d.items(info, n, d.skip)
+	case majorMap:
+		err = d.items(info, n, func() error {
+			if err := d.skip(); err != nil {
+				return err
+			}
+			return d.skip()
+		})
+	case majorTag:
+		err = d.items(1, 1, d.skip)
+	}
+	return err
+}
+
+// decode stores the next data item in v, which must be settable.
+func (d *decodeState) decode(v reflect.Value) error {
+	if d.off >= len(d.data) {
+		return io.ErrUnexpectedEOF
+	}
+	if b := d.data[d.off]; b == simpleNull || b == simpleUndefined {
+		d.off++
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == typeOfRaw:
+		start := d.off
+		if err := d.skip(); err != nil {
+			return err
+		}
+		v.SetBytes(append([]byte(nil), d.data[start:d.off]...))
+		return nil
+	case v.Type() == typeOfTime:
+		return d.decodeTime(v)
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		x, err := d.decodeInterface()
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	}
+
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case majorUint:
+		return setUint(v, n)
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return fmt.Errorf("cbor: -1-%d overflows int64", n)
+		}
+		return setInt(v, -1-int64(n))
+	case majorBytes:
+		b, err := d.readString(major, info, n)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte{}, b...))
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+			if len(b) > v.Len() {
+				return fmt.Errorf("cbor: byte string of %d bytes overflows %s", len(b), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+		default:
+			return typeError("byte string", v)
+		}
+	case majorText:
+		b, err := d.readString(major, info, n)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.String {
+			return typeError("text string", v)
+		}
+		v.SetString(string(b))
+	case majorArray:
+		return d.decodeArray(v, info, n)
+	case majorMap:
+		return d.decodeMap(v, info, n)
+	case majorTag:
+		// Tags other than date/time ones are ignored.
+		return d.items(1, 1, func() error { return d.decode(v) })
+	case majorSimple:
+		switch {
+		case info == 20 || info == 21:
+			if v.Kind() != reflect.Bool {
+				return typeError("boolean", v)
+			}
+			v.SetBool(info == 21)
+		case info >= 25 && info <= 27:
+			if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+				return typeError("float", v)
+			}
+			v.SetFloat(toFloat(info, n))
+		default:
+			return typeError(fmt.Sprintf("simple value %d", n), v)
+		}
+	}
+	return nil
+}
+
+func (d *decodeState) decodeArray(v reflect.Value, info byte, n uint64) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), 0, 0)
+		err := d.items(info, n, func() error {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(elem); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		v.Set(slice)
+	case reflect.Array:
+		i := 0
+		return d.items(info, n, func() error {
+			if i >= v.Len() {
+				return fmt.Errorf("cbor: array overflows %s", v.Type())
+			}
+			i++
+			return d.decode(v.Index(i - 1))
+		})
+	case reflect.Struct:
+		// The fields of the struct in order, as for a method taking several
+		// parameters.
+		fields := structFields(v.Type())
+		i := 0
+		return d.items(info, n, func() error {
+			if i >= len(fields) {
+				return fmt.Errorf("cbor: array overflows the %d fields of %s", len(fields), v.Type())
+			}
+			i++
+			return d.decode(v.Field(fields[i-1].index))
+		})
+	default:
+		return typeError("array", v)
+	}
+	return nil
+}
+
+func (d *decodeState) decodeMap(v reflect.Value, info byte, n uint64) error {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		return d.items(info, n, func() error {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+			return nil
+		})
+	case reflect.Struct:
+		// Entries without a matching field are ignored.
+		fields := structFields(v.Type())
+		return d.items(info, n, func() error {
+			key, err := d.decodeInterface()
+			if err != nil {
+				return err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return d.skip()
+			}
+			f, ok := fieldByName(fields, name)
+			if !ok {
+				return d.skip()
+			}
+			if err := d.decode(v.Field(f.index)); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			return nil
+		})
+	}
+	return typeError("map", v)
+}
+
+// decodeTime decodes a date/time, tagged or not, into v.
+func (d *decodeState) decodeTime(v reflect.Value) error {
+	x, err := d.decodeInterface()
+	if err != nil {
+		return err
+	}
+	t, err := toTime(x)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// decodeInterface decodes the next data item as the Go value stored in an
+// empty interface.
+func (d *decodeState) decodeInterface() (interface{}, error) {
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: -1-%d overflows int64", n)
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		b, err := d.readString(major, info, n)
+		return append([]byte{}, b...), err
+	case majorText:
+		b, err := d.readString(major, info, n)
+		return string(b), err
+	case majorArray:
+		a := []interface{}{}
+		err := d.items(info, n, func() error {
+			x, err := d.decodeInterface()
+			a = append(a, x)
+			return err
+		})
+		return a, err
+	case majorMap:
+		m := map[interface{}]interface{}{}
+		textKeys := true
+		err := d.items(info, n, func() error {
+			key, err := d.decodeInterface()
+			if err != nil {
+				return err
+			}
+			if key != nil && !reflect.TypeOf(key).Comparable() {
+				return fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			_, isText := key.(string)
+			textKeys = textKeys && isText
+			m[key], err = d.decodeInterface()
+			return err
+		})
+		if err != nil || !textKeys {
+			return m, err
+		}
+		sm := make(map[string]interface{}, len(m))
+		for key, value := range m {
+			sm[key.(string)] = value
+		}
+		return sm, nil
+	case majorTag:
+		var x interface{}
+		err := d.items(1, 1, func() (err error) {
+			x, err = d.decodeInterface()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if n == tagDateTime || n == tagEpochTime {
+			return toTime(x)
+		}
+		return x, nil
+	}
+	switch {
+	case info == 20 || info == 21:
+		return info == 21, nil
+	case info == 22 || info == 23:
+		return nil, nil
+	case info >= 25 && info <= 27:
+		return toFloat(info, n), nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+// toFloat returns the float of the bits n of a half, single or double
+// precision float, following the info of its initial byte.
+func toFloat(info byte, n uint64) float64 {
+	switch info {
+	case 25:
+		h := uint16(n)
+		exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+		var f float64
+		switch exp {
+		case 0:
+			f = math.Ldexp(mant, -24)
+		case 31:
+			f = math.Inf(1)
+			if mant != 0 {
+				f = math.NaN()
+			}
+		default:
+			f = math.Ldexp(mant+1024, exp-25)
+		}
+		if h&0x8000 != 0 {
+			f = -f
+		}
+		return f
+	case 26:
+		return float64(math.Float32frombits(uint32(n)))
+	}
+	return math.Float64frombits(n)
+}
+
+// toTime returns the time of a decoded date/time: a number of seconds since
+// the epoch or an RFC 3339 string.
+func toTime(x interface{}) (time.Time, error) {
+	switch x := x.(type) {
+	case time.Time:
+		return x, nil
+	case int64:
+		return time.Unix(x, 0).UTC(), nil
+	case uint64:
+		return time.Unix(int64(x), 0).UTC(), nil
+	case float64:
+		sec := math.Floor(x)
+		return time.Unix(int64(sec), int64(math.Round((x-sec)*1e9))).UTC(), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, x)
+	}
+	return time.Time{}, fmt.Errorf("cbor: cannot decode %T into time.Time", x)
+}
+
+func setUint(v reflect.Value, n uint64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n > math.MaxInt64 || v.OverflowInt(int64(n)) {
+			return fmt.Errorf("cbor: %d overflows %s", n, v.Type())
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.OverflowUint(n) {
+			return fmt.Errorf("cbor: %d overflows %s", n, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return typeError("unsigned integer", v)
+	}
+	return nil
+}
+
+func setInt(v reflect.Value, n int64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(n) {
+			return fmt.Errorf("cbor: %d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(n))
+	default:
+		return typeError("negative integer", v)
+	}
+	return nil
+}
+
+func typeError(cborType string, v reflect.Value) error {
+	return fmt.Errorf("cbor: cannot decode %s into %s", cborType, v.Type())
+}
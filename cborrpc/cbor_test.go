@@ -0,0 +1,236 @@
+package cborrpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Limard/rpcHttp"
+)
+
+// Examples of RFC 8949 appendix A.
+var examples = []struct {
+	value   interface{}
+	encoded string
+}{
+	{uint64(0), "00"},
+	{uint64(23), "17"},
+	{uint64(24), "1818"},
+	{uint64(1000), "1903e8"},
+	{uint64(1000000000000), "1b000000e8d4a51000"},
+	{uint64(18446744073709551615), "1bffffffffffffffff"},
+	{int64(-1), "20"},
+	{int64(-1000), "3903e7"},
+	{1.1, "fb3ff199999999999a"},
+	{false, "f4"},
+	{true, "f5"},
+	{nil, "f6"},
+	{[]byte{1, 2, 3, 4}, "4401020304"},
+	{"IETF", "6449455446"},
+	{"ü", "62c3bc"},
+	{[]interface{}{int64(1), []interface{}{int64(2), int64(3)}}, "8201820203"},
+	{map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}, "a26161016162820203"},
+	{time.Unix(1363896240, 0).UTC(), "c11a514b67b0"},
+}
+
+func TestExamples(t *testing.T) {
+	for _, example := range examples {
+		b, err := Marshal(example.value)
+		if err != nil || hex.EncodeToString(b) != example.encoded {
+			t.Errorf("Marshal(%#v) = %x, %v, want %s", example.value, b, err, example.encoded)
+		}
+		data, _ := hex.DecodeString(example.encoded)
+		want := example.value
+		if n, ok := want.(uint64); ok && n <= math.MaxInt64 {
+			// Integers are decoded as int64 when they fit.
+			want = int64(n)
+		}
+		var v interface{}
+		if err := Unmarshal(data, &v); err != nil || !reflect.DeepEqual(v, want) {
+			t.Errorf("Unmarshal(%s) = %#v, %v, want %#v", example.encoded, v, err, want)
+		}
+	}
+
+	// Decoding only: shorter floats, indefinite lengths and tag 0.
+	decoded := []struct {
+		encoded string
+		value   interface{}
+	}{
+		{"f93c00", 1.0},
+		{"f97bff", 65504.0},
+		{"f90001", 5.960464477539063e-08},
+		{"fa47c35000", 100000.0},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c1fb41d452d9ec200000", time.Unix(1363896240, 500000000).UTC()},
+	}
+	for _, example := range decoded {
+		data, _ := hex.DecodeString(example.encoded)
+		var v interface{}
+		if err := Unmarshal(data, &v); err != nil || !reflect.DeepEqual(v, example.value) {
+			t.Errorf("Unmarshal(%s) = %#v, %v, want %#v", example.encoded, v, err, example.value)
+		}
+	}
+	var f float64
+	if err := Unmarshal([]byte{0xf9, 0x7c, 0x00}, &f); err != nil || !math.IsInf(f, 1) {
+		t.Errorf("Expected +Inf, got %v, %v", f, err)
+	}
+}
+
+type Record struct {
+	Name   string     `cbor:"name"`
+	Count  int16      `cbor:"count"`
+	Blob   []byte     `cbor:"blob"`
+	When   time.Time  `cbor:"when"`
+	Tags   []string   `cbor:"tags,omitempty"`
+	Next   *Record    `cbor:"next,omitempty"`
+	Hidden string     `cbor:"-"`
+	Sum    [2]float32 `cbor:"sum"`
+}
+
+func TestStruct(t *testing.T) {
+	record := Record{
+		Name:  "a",
+		Count: -300,
+		Blob:  []byte("bytes"),
+		When:  time.Date(2021, 3, 4, 5, 6, 7, 250000000, time.UTC),
+		Next:  &Record{Name: "b", Blob: []byte{}, When: time.Unix(0, 0).UTC()},
+		Sum:   [2]float32{1.5, -2},
+	}
+	b, err := Marshal(&record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Record
+	if err := Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, record) {
+		t.Errorf("Wrong round trip:\ngot  %+v, %v\nwant %+v", got, err, record)
+	}
+
+	// {"NAME": "c", "count": 70000}
+	data, _ := hex.DecodeString("a2644e414d4561636563636f756e741a00011170")
+	if err := Unmarshal(data, &got); err == nil {
+		t.Errorf("Expected an overflow error, got %+v", got)
+	}
+	// {"NAME": "c", "unknown": 1}
+	data, _ = hex.DecodeString("a2644e414d45616367756e6b6e6f776e01")
+	got = Record{}
+	if err := Unmarshal(data, &got); err != nil || got.Name != "c" {
+		t.Errorf("Wrong case-insensitive decoding: %+v, %v", got, err)
+	}
+}
+
+var ErrResponseError = errors.New("response error")
+
+type Service1Request struct {
+	A int
+	B int
+}
+
+type Service1Response struct {
+	Result int
+}
+
+type Service1 struct {
+}
+
+func (t *Service1) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A * req.B
+	return nil
+}
+
+func (t *Service1) ResponseError(r *http.Request, req *Service1Request, res *Service1Response) error {
+	return ErrResponseError
+}
+
+func (t *Service1) Echo(r *http.Request, req *Record, res *Record) error {
+	*res = *req
+	return nil
+}
+
+func (t *Service1) Greet(name string, times int, res *string) error {
+	*res = strings.Repeat("hello "+name+" ", times)
+	return nil
+}
+
+func TestMethodParams(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	if err := s.RegisterServiceWithOptions(new(Service1), "", rpcHttp.WithMethodParams("Greet", "name", "times")); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	params := struct {
+		Times int    `cbor:"times"`
+		Name  string `cbor:"name"`
+	}{2, "go"}
+	var res string
+	if err := Call(server.URL, "Service1.Greet", &params, &res); err != nil || res != "hello go hello go " {
+		t.Errorf("Wrong by-name response: %q, %v", res, err)
+	}
+}
+
+func TestService(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	s.RegisterService(new(Service1), "")
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var res Service1Response
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d, %v", res.Result, err)
+	}
+	err := Call(server.URL, "Service1.ResponseError", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_SERVER || replyErr.Message != ErrResponseError.Error() {
+		t.Errorf("Expected error %d %q, got %v", E_SERVER, ErrResponseError, err)
+	}
+	record := Record{Name: "a", Blob: []byte{0xff}, When: time.Unix(1e9, 0).UTC()}
+	var echo Record
+	if err := Call(server.URL, "Service1.Echo", &record, &echo); err != nil || !reflect.DeepEqual(echo, record) {
+		t.Errorf("Wrong echo: %+v, %v", echo, err)
+	}
+
+	// {"cborrpc": "1.0", "method": "Service1.Multiply", "params": [[4, 2]], "id": 1}
+	// from another client, the params of the args by position.
+	body, _ := hex.DecodeString("a46763626f7272706363312e30666d6574686f64715365727669636531" +
+		"2e4d756c7469706c7966706172616d738182040262696401")
+	rsp, err := http.Post(server.URL, ContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if err := decodeClientResponse(rsp.Body, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d, %v", res.Result, err)
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("oops"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewClient(WithHTTPClient(server.Client()), WithClientLogger(rpcHttp.NewStdLogger(log.New(&buf, "", 0))))
+	var res Service1Response
+	err := client.Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_PARSE {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "level=warn msg=decodeClientResponse method=Service1.Multiply") {
+		t.Errorf("Wrong log: %q", buf.String())
+	}
+}
@@ -0,0 +1,174 @@
+package cborrpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+
+	"github.com/Limard/rpcHttp"
+)
+
+var ContentType = `application/cbor`
+
+// clientRequest represents a CBOR-RPC request sent by a client.
+type clientRequest struct {
+	Version string      `cbor:"cborrpc"`
+	Method  string      `cbor:"method"`
+	Params  interface{} `cbor:"params"`
+	Id      interface{} `cbor:"id"`
+}
+
+// clientResponse represents a CBOR-RPC response returned to a client.
+type clientResponse struct {
+	Id      interface{} `cbor:"id"`
+	Version string      `cbor:"cborrpc"`
+	Result  RawMessage  `cbor:"result"`
+	Error   RawMessage  `cbor:"error"`
+}
+
+// Client calls methods on CBOR-RPC servers. It is safe for concurrent use.
+// Call and CallContext use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	logger     rpcHttp.Logger
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithClientLogger sets the logger of the client.
+func WithClientLogger(logger rpcHttp.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+func encodeClientRequest(method string, args interface{}) ([]byte, error) {
+	c := &clientRequest{
+		Version: "1.0",
+		Method:  method,
+		Params:  args,
+		Id:      uint64(rand.Int63()),
+	}
+	return Marshal(c)
+}
+
+func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
+	var c clientResponse
+	buf, err := ioutil.ReadAll(r)
+	if err == nil {
+		err = Unmarshal(buf, &c)
+	}
+	if err != nil {
+		return &Error{
+			Code:    E_PARSE,
+			Message: err.Error()}
+	}
+
+	// Error
+	if len(c.Error) != 0 {
+		replyError := &Error{}
+		if err := Unmarshal(c.Error, replyError); err != nil {
+			return &Error{
+				Code:    E_PARSE,
+				Message: err.Error(),
+			}
+		}
+		return replyError
+	}
+
+	// Result
+	if len(c.Result) == 0 {
+		return &Error{
+			Code:    E_BAD_PARAMS,
+			Message: "result is null",
+		}
+	}
+	if err := Unmarshal(c.Result, reply); err != nil {
+		return &Error{
+			Code:    E_PARSE,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func Call(url string, method string, request interface{}, reply interface{}) (e error) {
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+// CallContext calls method with client. The deadline of ctx, if any, is sent
+// to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (c *Client) Call(url string, method string, request interface{}, reply interface{}) error {
+	return c.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (c *Client) CallContext(ctx context.Context, url string, method string, request interface{}, reply interface{}) error {
+	fields := []interface{}{"method", method, "codec", ContentType, "remote", url}
+	reqBuf, err := encodeClientRequest(method, request)
+	if err != nil {
+		c.logger.Error("encodeClientRequest", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBuf))
+	if err != nil {
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("http.Post", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_SERVER,
+			Message: err.Error()}
+	}
+	defer rsp.Body.Close()
+
+	err = decodeClientResponse(rsp.Body, reply)
+	if err != nil {
+		// The errors of the server are returned as is.
+		if replyErr, ok := err.(*Error); !ok || replyErr.Code == E_PARSE {
+			c.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+		}
+	}
+	return err
+}
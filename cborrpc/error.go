@@ -0,0 +1,25 @@
+package cborrpc
+
+import "encoding/json"
+
+const (
+	E_PARSE       = -32700
+	E_INVALID_REQ = -32600
+	E_NO_METHOD   = -32601
+	E_BAD_PARAMS  = -32602
+	E_INTERNAL    = -32603
+	E_SERVER      = -32000
+	E_TIMEOUT     = -32001
+	E_BUSY        = -32002
+)
+
+type Error struct {
+	Code    int         `cbor:"code"`    /* required */
+	Message string      `cbor:"message"` /* required */
+	Data    interface{} `cbor:"data"`    /* optional */
+}
+
+func (e *Error) Error() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
@@ -0,0 +1,167 @@
+package cborrpc
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Limard/rpcHttp"
+)
+
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{encSel: rpcHttp.DefaultEncoderSelector, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithLogger sets the logger of the codec.
+func WithLogger(logger rpcHttp.Logger) CodecOption {
+	return func(c *Codec) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// Codec creates a CodecRequest to process each request.
+type Codec struct {
+	encSel rpcHttp.EncoderSelector
+	logger rpcHttp.Logger
+}
+
+type serverRequest struct {
+	Version string      `cbor:"cborrpc"`
+	Method  string      `cbor:"method"`
+	Params  RawMessage  `cbor:"params"`
+	Id      interface{} `cbor:"id"`
+}
+
+type serverResponse struct {
+	Version string      `cbor:"cborrpc"`
+	Result  interface{} `cbor:"result,omitempty"`
+	Error   interface{} `cbor:"error,omitempty"`
+	Id      interface{} `cbor:"id"`
+}
+
+func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
+	var err error
+	req := new(serverRequest)
+	buf, e := ioutil.ReadAll(r.Body)
+	if e == nil {
+		e = Unmarshal(buf, req)
+	}
+	if e != nil {
+		err = &Error{
+			Code:    E_PARSE,
+			Message: e.Error(),
+			Data:    req,
+		}
+	}
+	r.Body.Close()
+	return &CodecRequest{
+		request: req,
+		err:     err,
+		encoder: c.encSel.Select(r),
+		logger:  c.logger,
+		remote:  r.RemoteAddr,
+	}
+}
+
+type CodecRequest struct {
+	request *serverRequest
+	err     error
+	encoder rpcHttp.Encoder
+	logger  rpcHttp.Logger
+	remote  string
+}
+
+func (c *CodecRequest) Method() (string, error) {
+	if c.err == nil {
+		return c.request.Method, nil
+	}
+	return "", c.err
+}
+
+// RequestID returns the id of the current request.
+func (c *CodecRequest) RequestID() interface{} {
+	return c.request.Id
+}
+
+// ReadRequest decodes the params into args. An array of params fills the
+// fields of args by position, an array holding a single map is read as the
+// whole args.
+func (c *CodecRequest) ReadRequest(args interface{}) error {
+	if c.err == nil && len(c.request.Params) != 0 {
+		if err := Unmarshal(c.request.Params, args); err != nil {
+			var params []RawMessage
+			if Unmarshal(c.request.Params, &params) == nil && len(params) == 1 {
+				err = Unmarshal(params[0], args)
+			}
+			if err != nil {
+				c.logger.Warn("invalid params", c.logFields("err", err)...)
+				c.err = &Error{
+					Code:    E_INVALID_REQ,
+					Message: err.Error(),
+					Data:    c.request.Params,
+				}
+			}
+		}
+	}
+	return c.err
+}
+
+// WriteResponse encodes the response and writes it to the ResponseWriter.
+func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}) {
+	res := &serverResponse{
+		Version: "1.0",
+		Result:  reply,
+		Id:      c.request.Id,
+	}
+	c.writeServerResponse(w, res)
+}
+
+func (c *CodecRequest) WriteErrorResponse(w http.ResponseWriter, code int, err error, data interface{}) {
+	objErr, ok := err.(*Error)
+	if !ok {
+		objErr = &Error{
+			Code:    code,
+			Message: err.Error(),
+			Data:    data,
+		}
+	}
+	res := &serverResponse{
+		Version: "1.0",
+		Error:   objErr,
+		Id:      c.request.Id,
+	}
+	c.writeServerResponse(w, res)
+}
+
+func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, res *serverResponse) {
+	if c.request.Id != nil {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/cbor")
+
+		buffer, err := Marshal(res)
+		if err != nil {
+			c.logger.Error("cbor Encode", c.logFields("err", err)...)
+			rpcHttp.WriteError(w, 400, err.Error())
+			return
+		}
+		if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+			c.logger.Warn("write response", c.logFields("err", err)...)
+		}
+	}
+}
+
+// logFields returns the log fields identifying the request followed by
+// keyvals.
+func (c *CodecRequest) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"method", c.request.Method, "codec", ContentType, "id", c.request.Id, "remote", c.remote}
+	return append(fields, keyvals...)
+}
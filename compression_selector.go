@@ -14,35 +14,43 @@ import (
 	"unicode"
 )
 
-// gzipWriter writes and closes the gzip writer.
+// gzipWriter writes to the gzip writer. Close flushes the compressed data.
 type gzipWriter struct {
 	w *gzip.Writer
 }
 
 func (gw *gzipWriter) Write(p []byte) (n int, err error) {
-	defer gw.w.Close()
 	return gw.w.Write(p)
 }
 
+func (gw *gzipWriter) Close() error {
+	return gw.w.Close()
+}
+
 // gzipEncoder implements the gzip compressed http encoder.
 type gzipEncoder struct {
 }
 
 func (enc *gzipEncoder) Encode(w http.ResponseWriter) io.Writer {
 	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Del("Content-Length")
 	return &gzipWriter{gzip.NewWriter(w)}
 }
 
-// flateWriter writes and closes the flate writer.
+// flateWriter writes to the flate writer. Close flushes the compressed data.
 type flateWriter struct {
 	w *flate.Writer
 }
 
 func (fw *flateWriter) Write(p []byte) (n int, err error) {
-	defer fw.w.Close()
 	return fw.w.Write(p)
 }
 
+func (fw *flateWriter) Close() error {
+	return fw.w.Close()
+}
+
 // flateEncoder implements the flate compressed http encoder.
 type flateEncoder struct {
 }
@@ -53,6 +61,8 @@ func (enc *flateEncoder) Encode(w http.ResponseWriter) io.Writer {
 		return w
 	}
 	w.Header().Set("Content-Encoding", "deflate")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Del("Content-Length")
 	return &flateWriter{fw}
 }
 
@@ -83,7 +93,7 @@ func (_ *CompressionSelector) Select(r *http.Request) Encoder {
 	switch acceptedEnc(r) {
 	case "gzip":
 		return &gzipEncoder{}
-	case "flate":
+	case "deflate":
 		return &flateEncoder{}
 	}
 	return DefaultEncoder
@@ -0,0 +1,54 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestIdentifier is implemented by a CodecRequest that can report the id
+// sent by the client. The server uses it to expose the id to service methods.
+type RequestIdentifier interface {
+	// Returns the request id as decoded by the codec, or nil if the request
+	// carries none.
+	RequestID() interface{}
+}
+
+type contextKey int
+
+const (
+	requestKey contextKey = iota
+	methodKey
+	requestIDKey
+)
+
+// newCallContext returns a context carrying the values of an RPC call.
+func newCallContext(parent context.Context, r *http.Request, method string, id interface{}) context.Context {
+	ctx := context.WithValue(parent, requestKey, r)
+	ctx = context.WithValue(ctx, methodKey, method)
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestFromContext returns the HTTP request of the RPC call, or nil if ctx
+// was not created by the server.
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey).(*http.Request)
+	return r
+}
+
+// MethodFromContext returns the resolved method name of the RPC call, as in
+// "Service.Method" with the default name mapper.
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodKey).(string)
+	return method
+}
+
+// RequestIDFromContext returns the request id sent by the client, or nil if
+// the codec does not report one.
+func RequestIDFromContext(ctx context.Context) interface{} {
+	return ctx.Value(requestIDKey)
+}
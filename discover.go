@@ -0,0 +1,295 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiscoverMethod is the reserved method returning the OpenRPC document of the
+// server, see https://spec.open-rpc.org/#service-discovery-method.
+const DiscoverMethod = "rpc.discover"
+
+// OpenRPCVersion is the version of the OpenRPC specification followed by the
+// documents returned by Discover.
+const OpenRPCVersion = "1.2.6"
+
+// OpenRPCDocument is an OpenRPC document describing the methods of a server.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []OpenRPCMethod   `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo holds the metadata of the API.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a method.
+type OpenRPCMethod struct {
+	Name           string                     `json:"name"`
+	Params         []OpenRPCContentDescriptor `json:"params"`
+	ParamStructure string                     `json:"paramStructure,omitempty"`
+	Result         *OpenRPCContentDescriptor  `json:"result,omitempty"`
+	Deprecated     bool                       `json:"deprecated,omitempty"`
+	Errors         []OpenRPCError             `json:"errors,omitempty"`
+}
+
+// OpenRPCContentDescriptor describes a parameter or a result.
+type OpenRPCContentDescriptor struct {
+	Name     string     `json:"name"`
+	Required bool       `json:"required,omitempty"`
+	Schema   JSONSchema `json:"schema"`
+}
+
+// OpenRPCError describes an error a method may return.
+type OpenRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// OpenRPCComponents holds the schemas referenced by the methods.
+type OpenRPCComponents struct {
+	Schemas map[string]JSONSchema `json:"schemas"`
+}
+
+// JSONSchema is a JSON Schema.
+type JSONSchema map[string]interface{}
+
+// SetDiscoverInfo sets the title and the version of the API reported by the
+// rpc.discover method.
+func (s *Server) SetDiscoverInfo(title, version string) {
+	s.discoverInfo = OpenRPCInfo{Title: title, Version: version}
+}
+
+// Discover returns the OpenRPC document of the registered methods, also
+// returned by the rpc.discover method. Hidden methods and aliases are left
+// out.
+func (s *Server) Discover() *OpenRPCDocument {
+	info := s.discoverInfo
+	if info.Title == "" {
+		info.Title = "rpcHttp"
+	}
+	if info.Version == "" {
+		info.Version = "0.0.0"
+	}
+	doc := &OpenRPCDocument{
+		OpenRPC:    OpenRPCVersion,
+		Info:       info,
+		Methods:    []OpenRPCMethod{},
+		Components: OpenRPCComponents{Schemas: make(map[string]JSONSchema)},
+	}
+	g := &schemaGenerator{
+		schemas: doc.Components.Schemas,
+		names:   make(map[reflect.Type]string),
+	}
+
+	m := s.services
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, service := range m.services {
+		for mn, mv := range service.methods {
+			if mv.hidden || mn != m.key(mv.name) {
+				// Hidden method or alias.
+				continue
+			}
+			method := OpenRPCMethod{
+				Name:           m.nameMapper().Join(service.name, mv.name),
+				Params:         g.params(mv.argsType, mv.params),
+				ParamStructure: "by-name",
+				Result: &OpenRPCContentDescriptor{
+					Name:   "result",
+					Schema: g.schema(mv.replyType),
+				},
+				Deprecated: mv.deprecated != "",
+				Errors:     s.methodErrors(service, mv),
+			}
+			if mv.params {
+				// The parameters of the method are also named.
+				method.ParamStructure = "either"
+			} else if mv.argsType.Kind() != reflect.Struct {
+				method.ParamStructure = "by-position"
+			}
+			doc.Methods = append(doc.Methods, method)
+		}
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool {
+		return doc.Methods[i].Name < doc.Methods[j].Name
+	})
+	return doc
+}
+
+// newDiscoverService returns the built-in service of the rpc.discover method.
+// It is called like the registered methods, through the interceptors and the
+// limits, but is not in the service map so that it is found whatever the name
+// mapper and never listed.
+func (s *Server) newDiscoverService() *service {
+	fn := func(ctx context.Context, args *struct{}, reply *OpenRPCDocument) error {
+		*reply = *s.Discover()
+		return nil
+	}
+	spec := newServiceMethod(DiscoverMethod, reflect.ValueOf(fn))
+	spec.hidden = true
+	return &service{
+		name:    "rpc",
+		methods: map[string]*serviceMethod{DiscoverMethod: spec},
+	}
+}
+
+// methodErrors returns the errors the server may return for a method.
+func (s *Server) methodErrors(serviceSpec *service, methodSpec *serviceMethod) []OpenRPCError {
+	errors := []OpenRPCError{
+		{E_BAD_PARAMS, "Invalid params"},
+		{E_INTERNAL, "Internal error"},
+		{E_SERVER, "Server error"},
+	}
+	if s.timeout > 0 || serviceSpec.timeout > 0 || methodSpec.timeout > 0 {
+		errors = append(errors, OpenRPCError{E_TIMEOUT, "Timeout"})
+	}
+	if s.limiter != nil || serviceSpec.limiter != nil || methodSpec.limiter != nil {
+		errors = append(errors, OpenRPCError{E_BUSY, "Server busy"})
+	}
+	return errors
+}
+
+// schemaGenerator generates the JSON Schemas of Go types, as encoded by
+// encoding/json. Named struct types are added to schemas and referenced.
+type schemaGenerator struct {
+	schemas map[string]JSONSchema
+	names   map[reflect.Type]string
+}
+
+var (
+	typeOfTime          = reflect.TypeOf(time.Time{})
+	typeOfJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// params returns the parameters of a method taking args of type t: the
+// fields of an args struct, or a single parameter otherwise.
+func (g *schemaGenerator) params(t reflect.Type, positional bool) []OpenRPCContentDescriptor {
+	if t.Kind() != reflect.Struct {
+		return []OpenRPCContentDescriptor{{Name: "params", Required: true, Schema: g.schema(t)}}
+	}
+	params := []OpenRPCContentDescriptor{}
+	for _, field := range jsonFields(t) {
+		params = append(params, OpenRPCContentDescriptor{
+			Name:     field.name,
+			Required: positional,
+			Schema:   g.schema(field.typ),
+		})
+	}
+	return params
+}
+
+// schema returns the JSON Schema of type t.
+func (g *schemaGenerator) schema(t reflect.Type) JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == typeOfTime:
+		return JSONSchema{"type": "string", "format": "date-time"}
+	case t.Implements(typeOfJSONMarshaler) || reflect.PtrTo(t).Implements(typeOfJSONMarshaler):
+		// Any value.
+		return JSONSchema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return JSONSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return JSONSchema{"type": "number"}
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return JSONSchema{"type": "string", "contentEncoding": "base64"}
+		}
+		return JSONSchema{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return JSONSchema{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.schemaName(t)
+			g.names[t] = name
+			// Registered before generating, for recursive types.
+			g.schemas[name] = nil
+			g.schemas[name] = g.structSchema(t)
+		}
+		return JSONSchema{"$ref": "#/components/schemas/" + name}
+	}
+	// Interfaces and anything else.
+	return JSONSchema{}
+}
+
+// schemaName returns a unique name for the named type t.
+func (g *schemaGenerator) schemaName(t reflect.Type) string {
+	name := t.Name()
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[name]; !taken {
+			return name
+		}
+		name = t.Name() + strconv.Itoa(i)
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) JSONSchema {
+	properties := make(map[string]interface{})
+	for _, field := range jsonFields(t) {
+		properties[field.name] = g.schema(field.typ)
+	}
+	return JSONSchema{"type": "object", "properties": properties}
+}
+
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields returns the fields of struct type t encoded by encoding/json,
+// including the promoted fields of embedded structs.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(embedded)...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: field.Type})
+	}
+	return fields
+}
@@ -67,13 +67,55 @@ When a service is registered, the server inspects the service methods
 and make available the ones that follow these rules:
 
 	- The method name is exported.
-	- The method has three arguments: *http.Request, *args, *reply.
-	- All three arguments are pointers.
-	- The second and third arguments are exported or local.
+	- The method has two arguments *args, *reply, optionally preceded by
+	  either context.Context or *http.Request (and http.ResponseWriter).
+	- The *args and *reply arguments are pointers.
+	- The *args and *reply arguments are exported or local.
 	- The method has return type error.
 
 All other methods are ignored.
 
+The *args argument may be replaced by one or more parameters of any exported
+type, as in:
+
+	func (h *HelloService) Greet(name string, times int, reply *string) error
+
+Such methods are only registered with the WithPositionalParams or
+WithMethodParams options, or with RegisterFunc:
+
+	s.RegisterServiceWithOptions(new(HelloService), "", rpc.WithPositionalParams())
+
+The parameters are gathered in an args struct. Codecs decoding params by name
+see them as "p0", "p1"... unless named with the WithMethodParams option, and
+the JSON-RPC 2.0 codec also decodes them from a by-position array.
+
+A method taking a context.Context receives a context that is canceled when
+the client connection closes. The HTTP request, the resolved method name and
+the request id are available through RequestFromContext, MethodFromContext
+and RequestIDFromContext:
+
+	func (h *HelloService) Say(ctx context.Context, args *HelloArgs, reply *HelloReply) error {
+		log.Println(rpc.MethodFromContext(ctx), rpc.RequestIDFromContext(ctx))
+		reply.Message = "Hello, " + args.Who + "!"
+		return nil
+	}
+
+Interceptors registered with Server.Use run around every method call, in
+registration order, whatever the codec. They see the resolved method and the
+decoded args, and may short-circuit the call or inspect its result:
+
+	s.Use(func(ctx context.Context, call *rpc.CallInfo, next rpc.Handler) (int, error, interface{}) {
+		if call.Request.Header.Get("Authorization") == "" {
+			return rpc.E_INVALID_REQ, errors.New("unauthorized"), nil
+		}
+		return next(ctx, call)
+	})
+
+The reserved method "rpc.discover" returns an OpenRPC document describing the
+visible methods, their params and result as JSON Schemas, and the error codes
+they may return. It is called like the other methods, through the
+interceptors and the limits. Server.Discover returns the same document.
+
 Gorilla has packages with common RPC codecs. Check out their documentation:
 
 	JSON: http://gorilla-web.appspot.com/pkg/rpc/json
@@ -12,10 +12,26 @@ import (
 
 // Encoder interface contains the encoder for http response.
 // Eg. gzip, flate compressions.
+//
+// The writer returned by Encode may implement io.Closer, in which case it is
+// closed once the response is written, e.g. to flush a compressor.
 type Encoder interface {
 	Encode(w http.ResponseWriter) io.Writer
 }
 
+// EncodeResponse writes the response body through the writer returned by
+// encoder, then closes that writer if it is an io.Closer.
+func EncodeResponse(w http.ResponseWriter, encoder Encoder, body []byte) error {
+	writer := encoder.Encode(w)
+	_, err := writer.Write(body)
+	if closer, ok := writer.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
 type encoder struct {
 }
 
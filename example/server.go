@@ -18,6 +18,7 @@ var host = "127.0.0.1:8199"
 
 func main() {
 	s := rpcHttp.NewServer()
+	s.SetLogger(rpcHttp.NewStdLogger(nil))
 	s.RegisterCodec(jsonrpc2.NewCodec(), jsonrpc2.ContentType)
 	s.RegisterCodec(bsonrpc.NewCodec(), bsonrpc.ContentType)
 	s.RegisterCodec(msgpackrpc.NewCodec(), msgpackrpc.ContentType)
@@ -3,12 +3,12 @@ module github.com/Limard/rpcHttp
 go 1.19
 
 require (
+	github.com/golang/protobuf v1.3.1
 	github.com/vmihailenco/msgpack v4.0.4+incompatible
 	gopkg.in/mgo.v2 v2.0.0-20190816093944-a6b53ec6cb22
 )
 
 require (
-	github.com/golang/protobuf v1.3.1 // indirect
 	golang.org/x/net v0.0.0-20190603091049-60506f45cf65 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
@@ -0,0 +1,45 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// CallInfo describes a method call passed through the interceptors.
+type CallInfo struct {
+	// The name of the service, as registered.
+	Service string
+	// The resolved method name, as in "Service.Method" with the default
+	// name mapper.
+	Method string
+	// The HTTP request carrying the call.
+	Request *http.Request
+	// The decoded args, a pointer to the method args type.
+	Args interface{}
+	// The reply, a pointer to the method reply type. It is filled in by the
+	// method and written by the codec if the call succeeds.
+	Reply interface{}
+}
+
+// Handler invokes a method call. It returns the error code, the error and the
+// error data the same way a service method does; a nil error means success.
+type Handler func(ctx context.Context, call *CallInfo) (int, error, interface{})
+
+// Interceptor wraps the invocation of a service method.
+//
+// An interceptor may inspect or modify the call before calling next, return
+// an error without calling next to short-circuit the call, or inspect the
+// reply and error returned by next before the codec writes the response.
+type Interceptor func(ctx context.Context, call *CallInfo, next Handler) (int, error, interface{})
+
+// chainInterceptor returns a Handler calling interceptor around next.
+func chainInterceptor(interceptor Interceptor, next Handler) Handler {
+	return func(ctx context.Context, call *CallInfo) (int, error, interface{}) {
+		return interceptor(ctx, call, next)
+	}
+}
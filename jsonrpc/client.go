@@ -6,12 +6,20 @@
 package jsonrpc
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/rand"
+	"net/http"
+
+	"github.com/Limard/rpcHttp"
 )
 
+var ContentType = `application/json`
+
 // ----------------------------------------------------------------------------
 // Request and Response
 // ----------------------------------------------------------------------------
@@ -23,15 +31,54 @@ type clientRequest struct {
 	// Object to pass as request parameter to the method.
 	Params [1]interface{} `json:"params"`
 	// The request id. This can be of any type. It is used to match the
-	// response with the request that it is replying to.
-	Id uint64 `json:"id"`
+	// response with the request that it is replying to. It is null for
+	// notifications.
+	Id interface{} `json:"id"`
 }
 
 // clientResponse represents a JSON-RPC response returned to a client.
 type clientResponse struct {
 	Result *json.RawMessage `json:"result"`
 	Error  interface{}      `json:"error"`
-	Id     uint64           `json:"id"`
+	Id     json.RawMessage  `json:"id"`
+}
+
+// Client calls methods on JSON-RPC servers. It is safe for concurrent use.
+// The Call and Notify functions use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	useNumber  bool
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithClientUseNumber makes the client decode the numbers of the result and
+// of the error held in interface values as json.Number instead of float64,
+// so that large integers keep their precision.
+func WithClientUseNumber() ClientOption {
+	return func(c *Client) {
+		c.useNumber = true
+	}
 }
 
 // EncodeClientRequest encodes parameters for a JSON-RPC client request.
@@ -47,15 +94,167 @@ func EncodeClientRequest(method string, args interface{}) ([]byte, error) {
 // DecodeClientResponse decodes the response body of a client request into
 // the interface reply.
 func DecodeClientResponse(r io.Reader, reply interface{}) error {
+	return NewClient().DecodeClientResponse(r, reply)
+}
+
+// DecodeClientResponse decodes the response body of a client request into
+// the interface reply, with the options of the client.
+func (cl *Client) DecodeClientResponse(r io.Reader, reply interface{}) error {
+	return cl.decodeResponse(r, nil, reply)
+}
+
+// decodeResponse decodes the response body of the client request of the
+// given id into the interface reply. It returns a *MismatchError if the id
+// of the response does not match, unless id is nil.
+func (cl *Client) decodeResponse(r io.Reader, id interface{}, reply interface{}) error {
 	var c clientResponse
-	if err := json.NewDecoder(r).Decode(&c); err != nil {
+	if err := cl.decode(r, &c); err != nil {
 		return err
 	}
+	// An error response has a null id if the server could not read the
+	// request id.
+	if id != nil && !(c.Error != nil && isNull(c.Id)) {
+		want, err := json.Marshal(id)
+		if err != nil {
+			return err
+		}
+		var got bytes.Buffer
+		if err := json.Compact(&got, c.Id); err != nil || !bytes.Equal(got.Bytes(), want) {
+			return &MismatchError{Want: string(want), Got: string(c.Id)}
+		}
+	}
 	if c.Error != nil {
 		return &Error{Data: c.Error}
 	}
 	if c.Result == nil {
 		return fmt.Errorf("Unexpected null result")
 	}
-	return json.Unmarshal(*c.Result, reply)
+	return unmarshal(*c.Result, reply, cl.useNumber)
+}
+
+// decode decodes the JSON response read from r into v, with the options of
+// the client.
+func (cl *Client) decode(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	if cl.useNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
+
+// Call calls method on the JSON-RPC server at url with http.DefaultClient,
+// decoding the result into reply. An error returned by the method is an
+// *Error holding the error member of the response.
+func Call(url string, method string, request interface{}, reply interface{}) error {
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+// CallEx calls method like Call, with client.
+func CallEx(client *http.Client, url string, method string, request interface{}, reply interface{}) error {
+	return CallContext(context.Background(), client, url, method, request, reply)
+}
+
+// CallContext calls method with client like CallEx. The deadline of ctx, if
+// any, is sent to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request interface{}, reply interface{}) error {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (cl *Client) Call(url string, method string, request interface{}, reply interface{}) error {
+	return cl.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (cl *Client) CallContext(ctx context.Context, url string, method string, request interface{}, reply interface{}) error {
+	c := &clientRequest{
+		Method: method,
+		Params: [1]interface{}{request},
+		Id:     uint64(rand.Int63()),
+	}
+	status, body, err := post(ctx, cl.httpClient, url, c)
+	if err != nil {
+		return err
+	}
+	err = cl.decodeResponse(bytes.NewReader(body), c.Id, reply)
+	if _, ok := err.(*Error); !ok && err != nil && status/100 != 2 {
+		// Not a JSON-RPC response, e.g. an unsupported Content-Type.
+		return fmt.Errorf("jsonrpc: %d %s: %s", status, http.StatusText(status), bytes.TrimSpace(body))
+	}
+	return err
+}
+
+// Notify sends a notification, a request without response, of method to the
+// JSON-RPC server at url with http.DefaultClient.
+func Notify(url string, method string, request interface{}) error {
+	return NotifyContext(context.Background(), http.DefaultClient, url, method, request)
+}
+
+// NotifyEx sends a notification like Notify, with client.
+func NotifyEx(client *http.Client, url string, method string, request interface{}) error {
+	return NotifyContext(context.Background(), client, url, method, request)
+}
+
+// NotifyContext sends a notification with client like NotifyEx. The server
+// only answers a notification it could not serve, with an error returned as
+// an *Error.
+func NotifyContext(ctx context.Context, client *http.Client, url string, method string, request interface{}) error {
+	return NewClient(WithHTTPClient(client)).NotifyContext(ctx, url, method, request)
+}
+
+// Notify sends a notification of method to the server at url.
+func (cl *Client) Notify(url string, method string, request interface{}) error {
+	return cl.NotifyContext(context.Background(), url, method, request)
+}
+
+// NotifyContext sends a notification like Notify. The server only answers a
+// notification it could not serve, with an error returned as an *Error.
+func (cl *Client) NotifyContext(ctx context.Context, url string, method string, request interface{}) error {
+	c := &clientRequest{
+		Method: method,
+		Params: [1]interface{}{request},
+	}
+	status, body, err := post(ctx, cl.httpClient, url, c)
+	if err != nil {
+		return err
+	}
+	if status/100 == 2 && len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var res clientResponse
+	if err := cl.decode(bytes.NewReader(body), &res); err != nil || res.Error == nil {
+		return fmt.Errorf("jsonrpc: %d %s: %s", status, http.StatusText(status), bytes.TrimSpace(body))
+	}
+	return &Error{Data: res.Error}
+}
+
+// post sends the client request to url and returns the response status and
+// body.
+func post(ctx context.Context, client *http.Client, url string, c *clientRequest) (int, []byte, error) {
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(buf))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rsp.Body.Close()
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return rsp.StatusCode, body, nil
 }
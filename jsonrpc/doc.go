@@ -51,6 +51,15 @@ Response format is:
 	id:
 		The same id as the request it is responding to.
 
+A client calls a method with Call, or CallEx to use its own http.Client:
+
+	var reply HelloReply
+	err := json.Call("http://localhost/rpc", "HelloService.Say", &HelloArgs{"John"}, &reply)
+
+Notify and NotifyEx send a notification, a request with a null id, to which
+the server does not respond. An error returned by the method is an *Error
+holding the error member of the response.
+
 Check the gorilla/rpc documentation for more details:
 
 	http://gorilla-web.appspot.com/pkg/rpc
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -130,3 +131,122 @@ func TestClientNullResult(t *testing.T) {
 		t.Fatalf("Unexpected error: %s", err)
 	}
 }
+
+type Service2 struct {
+	calls chan int
+}
+
+func (t *Service2) Record(r *http.Request, req *Service1Request, res *Service1Response) error {
+	t.calls <- req.A
+	return nil
+}
+
+func TestCall(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(new(Service1), "")
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var res Service1Response
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d, %v", res.Result, err)
+	}
+	if err := CallEx(server.Client(), server.URL, "Service1.ResponseError", &Service1Request{4, 2}, &res); err == nil || err.Error() != ErrResponseError.Error() {
+		t.Errorf("Expected to get %q, but got %v", ErrResponseError, err)
+	}
+	err := CallEx(server.Client(), server.URL, "Service1.ResponseJsonError", &Service1Request{4, 2}, &res)
+	if jsonErr, ok := err.(*Error); !ok || !reflect.DeepEqual(jsonErr.Data, ErrResponseJsonError.Data) {
+		t.Errorf("Expected jsonErr to be %q, but got %v", ErrResponseJsonError, err)
+	}
+	if err := Call(server.URL, "Service1.Unknown", &Service1Request{4, 2}, &res); err == nil {
+		t.Error("Expected an error on unknown method")
+	}
+}
+
+func TestCallID(t *testing.T) {
+	body := `{"result": {"Result": 8}, "error": null, "id": 1}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	var res Service1Response
+	err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res)
+	if mismatch, ok := err.(*MismatchError); !ok || mismatch.Got != "1" {
+		t.Errorf("Expected an id mismatch, got %v", err)
+	}
+	// The server could not read the id of the request.
+	body = `{"result": null, "error": "invalid request", "id": null}`
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err == nil || err.Error() != "invalid request" {
+		t.Errorf("Expected the error of the server, got %v", err)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	service := &Service2{calls: make(chan int, 1)}
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(service, "")
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	if err := Notify(server.URL, "Service2.Record", &Service1Request{A: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if a := <-service.calls; a != 3 {
+		t.Errorf("Wrong notification params: got %d, want 3", a)
+	}
+	if err := NotifyEx(server.Client(), server.URL, "Service2.Unknown", &Service1Request{A: 3}); err == nil {
+		t.Error("Expected an error on unknown method")
+	} else if _, ok := err.(*Error); !ok {
+		t.Errorf("Expected err to be of a *json.Error type, got %v", err)
+	}
+}
+
+type Service3Request struct {
+	Value interface{}
+}
+
+type Service3 struct {
+}
+
+func (t *Service3) Echo(r *http.Request, req *Service3Request, res *Service3Request) error {
+	if _, ok := req.Value.(json.Number); !ok {
+		return fmt.Errorf("value is a %T", req.Value)
+	}
+	res.Value = req.Value
+	return nil
+}
+
+func TestUseNumber(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(WithUseNumber()), "application/json")
+	s.RegisterService(new(Service3), "")
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	client := NewClient(WithHTTPClient(server.Client()), WithClientUseNumber())
+	var res Service3Request
+	if err := client.Call(server.URL, "Service3.Echo", &Service3Request{Value: int64(9007199254740993)}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Value != json.Number("9007199254740993") {
+		t.Errorf("Wrong result: %#v", res.Value)
+	}
+	// The default client decodes float64.
+	if err := Call(server.URL, "Service3.Echo", &Service3Request{Value: int64(1)}, &res); err != nil || res.Value != float64(1) {
+		t.Errorf("Wrong default result: %#v, %v", res.Value, err)
+	}
+
+	// The error of a notification.
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": null, "error": {"code": 9007199254740993}, "id": null}`))
+	}))
+	defer errServer.Close()
+	err := client.Notify(errServer.URL, "Service3.Echo", &Service3Request{Value: 1})
+	if jsonErr, ok := err.(*Error); !ok || !reflect.DeepEqual(jsonErr.Data, map[string]interface{}{"code": json.Number("9007199254740993")}) {
+		t.Errorf("Wrong notification error: %#v", err)
+	}
+}
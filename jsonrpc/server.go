@@ -6,6 +6,7 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,6 +28,17 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%v", e.Data)
 }
 
+// MismatchError is returned by the client when the id of a response does not
+// match the id of its request.
+type MismatchError struct {
+	// The expected and received ids, as JSON.
+	Want, Got string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("jsonrpc: response id mismatch: got %s, want %s", e.Got, e.Want)
+}
+
 // ----------------------------------------------------------------------------
 // Request and Response
 // ----------------------------------------------------------------------------
@@ -59,17 +71,34 @@ type serverResponse struct {
 // ----------------------------------------------------------------------------
 
 // NewCodec returns a new JSON Codec.
-func NewCodec() *Codec {
-	return &Codec{}
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithUseNumber makes the codec decode the numbers of the params held in
+// interface values as json.Number instead of float64, so that large integers
+// keep their precision.
+func WithUseNumber() CodecOption {
+	return func(c *Codec) {
+		c.useNumber = true
+	}
 }
 
 // Codec creates a CodecRequest to process each request.
 type Codec struct {
+	useNumber bool
 }
 
 // NewRequest returns a CodecRequest.
 func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
-	return newCodecRequest(r)
+	return newCodecRequest(r, c.useNumber)
 }
 
 // ----------------------------------------------------------------------------
@@ -77,18 +106,19 @@ func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
 // ----------------------------------------------------------------------------
 
 // newCodecRequest returns a new CodecRequest.
-func newCodecRequest(r *http.Request) rpcHttp.CodecRequest {
+func newCodecRequest(r *http.Request, useNumber bool) rpcHttp.CodecRequest {
 	// Decode the request body and check if RPC method is valid.
 	req := new(serverRequest)
 	err := json.NewDecoder(r.Body).Decode(req)
 	r.Body.Close()
-	return &CodecRequest{request: req, err: err}
+	return &CodecRequest{request: req, err: err, useNumber: useNumber}
 }
 
 // CodecRequest decodes and encodes a single request.
 type CodecRequest struct {
-	request *serverRequest
-	err     error
+	request   *serverRequest
+	err       error
+	useNumber bool
 }
 
 // Method returns the RPC method for the current request.
@@ -101,6 +131,14 @@ func (c *CodecRequest) Method() (string, error) {
 	return "", c.err
 }
 
+// RequestID returns the raw JSON id of the current request.
+func (c *CodecRequest) RequestID() interface{} {
+	if c.request.Id == nil {
+		return nil
+	}
+	return *c.request.Id
+}
+
 // ReadRequest fills the request object for the RPC method.
 func (c *CodecRequest) ReadRequest(args interface{}) error {
 	if c.err == nil {
@@ -108,7 +146,7 @@ func (c *CodecRequest) ReadRequest(args interface{}) error {
 			// JSON params is array value. RPC params is struct.
 			// Unmarshal into array containing the request struct.
 			params := [1]interface{}{args}
-			c.err = json.Unmarshal(*c.request.Params, &params)
+			c.err = unmarshal(*c.request.Params, &params, c.useNumber)
 		} else {
 			c.err = errors.New("rpc: method request ill-formed: missing params field")
 		}
@@ -153,3 +191,14 @@ func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, status int, re
 		rpcHttp.WriteError(w, 400, err.Error())
 	}
 }
+
+// unmarshal is json.Unmarshal, decoding the numbers of interface values as
+// json.Number if useNumber.
+func unmarshal(data []byte, v interface{}, useNumber bool) error {
+	if !useNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
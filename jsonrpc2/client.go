@@ -7,11 +7,13 @@ package jsonrpc2
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
+	"strconv"
+
+	"github.com/Limard/rpcHttp"
 )
 
 var ContentType = `application/json`
@@ -33,44 +35,131 @@ type clientRequest struct {
 
 	// The request id. This can be of any type. It is used to match the
 	// response with the request that it is replying to.
-	Id uint64 `json:"id"`
+	Id interface{} `json:"id"`
 }
 
 // clientResponse represents a JSON-RPC response returned to a client.
 type clientResponse struct {
-	Id      interface{}      `json:"id"`
+	Id      json.RawMessage  `json:"id"`
 	Version string           `json:"jsonrpc"`
 	Result  *json.RawMessage `json:"result"`
 	Error   *json.RawMessage `json:"error"`
 }
 
-// encodeClientRequest encodes parameters for a JSON-RPC client request.
-func encodeClientRequest(method string, args interface{}) ([]byte, error) {
-	c := &clientRequest{
+// Client calls methods on JSON-RPC 2.0 servers. It is safe for concurrent
+// use. Call, CallEx and CallContext use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	logger     rpcHttp.Logger
+	ids        IDGenerator
+	useNumber  bool
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient, logger: rpcHttp.NopLogger, ids: defaultIDGenerator}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithIDGenerator sets the generator of the request ids. The ids are
+// sequential numbers, shared by the clients without this option, by default.
+func WithIDGenerator(gen IDGenerator) ClientOption {
+	return func(c *Client) {
+		if gen != nil {
+			c.ids = gen
+		}
+	}
+}
+
+// WithClientUseNumber makes the client decode the numbers of the result and
+// of the error data held in interface values as json.Number instead of
+// float64, so that large integers keep their precision.
+func WithClientUseNumber() ClientOption {
+	return func(c *Client) {
+		c.useNumber = true
+	}
+}
+
+// WithClientLogger sets the logger of the client.
+func WithClientLogger(logger rpcHttp.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// newRequest returns a JSON-RPC client request with a new id.
+func (cl *Client) newRequest(method string, args interface{}) *clientRequest {
+	return &clientRequest{
 		Version: "2.0",
 		Method:  method,
 		Params:  args,
-		Id:      uint64(rand.Int63()),
+		Id:      cl.ids.NextID(),
 	}
-	return json.Marshal(c)
+}
+
+// encodeClientRequest encodes parameters for a JSON-RPC client request.
+func encodeClientRequest(method string, args interface{}) ([]byte, error) {
+	return json.Marshal(NewClient().newRequest(method, args))
 }
 
 // decodeClientResponse decodes the response body of a client request into
 // the interface reply.
 func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
+	return NewClient().decodeResponse(r, nil, reply)
+}
+
+// decodeResponse decodes the response body of the client request with the
+// given id into the interface reply. It returns a *MismatchError if the
+// response is not a JSON-RPC 2.0 response to that request. A nil id is not
+// checked.
+func (cl *Client) decodeResponse(r io.Reader, id interface{}, reply interface{}) (e error) {
 	var c clientResponse
 	if err := json.NewDecoder(r).Decode(&c); err != nil {
-		fmt.Println("decodeClientResponse.json.NewDecoder:", err)
 		return &Error{
 			Code:    E_PARSE,
 			Message: err.Error()}
 	}
 
+	if c.Version != Version {
+		return &MismatchError{Member: "jsonrpc", Want: strconv.Quote(Version), Got: strconv.Quote(c.Version)}
+	}
+	// An error response has a null id if the server could not read the
+	// request id.
+	if id != nil && !(c.Error != nil && isNull(c.Id)) {
+		want, err := json.Marshal(id)
+		if err != nil {
+			return &Error{
+				Code:    E_INVALID_REQ,
+				Message: err.Error()}
+		}
+		var got bytes.Buffer
+		if err := json.Compact(&got, c.Id); err != nil || !bytes.Equal(got.Bytes(), want) {
+			return &MismatchError{Member: "id", Want: string(want), Got: string(c.Id)}
+		}
+	}
+
 	// Error
 	if c.Error != nil {
 		replyError := &Error{}
-		if err := json.Unmarshal(*c.Error, replyError); err != nil {
-			fmt.Println("decodeClientResponse.json.Unmarshal:", e)
+		if err := unmarshal(*c.Error, replyError, false, cl.useNumber); err != nil {
 			return &Error{
 				Code:    E_PARSE,
 				Message: string(*c.Error),
@@ -86,7 +175,7 @@ func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
 			Message: ErrNullResult.Error(),
 		}
 	}
-	if err := json.Unmarshal(*c.Result, reply); err != nil {
+	if err := unmarshal(*c.Result, reply, false, cl.useNumber); err != nil {
 		return &Error{
 			Code:    E_PARSE,
 			Message: err.Error(),
@@ -97,41 +186,49 @@ func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
 }
 
 func Call(url string, method string, request interface{}, reply interface{}) (e error) {
-	jsonReqBuf, err := encodeClientRequest(method, request)
-	if err != nil {
-		fmt.Println("encodeClientRequest:", e)
-		return &Error{
-			Code:    E_INVALID_REQ,
-			Message: err.Error()}
-	}
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+func CallEx(client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
+	return CallContext(context.Background(), client, url, method, request, reply)
+}
 
-	jsonReqBufR := bytes.NewReader(jsonReqBuf)
-	rsp, err := http.Post(url, ContentType, jsonReqBufR)
+// CallContext calls method with client like CallEx. The deadline of ctx, if
+// any, is sent to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (cl *Client) Call(url string, method string, request interface{}, reply interface{}) error {
+	return cl.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (cl *Client) CallContext(ctx context.Context, url string, method string, request interface{}, reply interface{}) error {
+	c := cl.newRequest(method, request)
+	fields := []interface{}{"method", method, "codec", ContentType, "id", c.Id, "remote", url}
+	jsonReqBuf, err := json.Marshal(c)
 	if err != nil {
-		fmt.Println("http.Post:", e)
+		cl.logger.Error("encodeClientRequest", append(fields, "err", err)...)
 		return &Error{
-			Code:    E_SERVER,
+			Code:    E_INVALID_REQ,
 			Message: err.Error()}
 	}
 
-	defer rsp.Body.Close()
-
-	return decodeClientResponse(rsp.Body, reply)
-}
-
-func CallEx(client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
-	jsonReqBuf, err := encodeClientRequest(method, request)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonReqBuf))
 	if err != nil {
-		fmt.Println("encodeClientRequest:", e)
 		return &Error{
 			Code:    E_INVALID_REQ,
 			Message: err.Error()}
 	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
 
-	jsonReqBufR := bytes.NewReader(jsonReqBuf)
-	rsp, err := client.Post(url, ContentType, jsonReqBufR)
+	rsp, err := cl.httpClient.Do(req)
 	if err != nil {
-		fmt.Println("http.Post:", e)
+		cl.logger.Error("http.Post", append(fields, "err", err)...)
 		return &Error{
 			Code:    E_SERVER,
 			Message: err.Error()}
@@ -139,7 +236,13 @@ func CallEx(client *http.Client, url string, method string, request interface{},
 
 	defer rsp.Body.Close()
 
-	return decodeClientResponse(rsp.Body, reply)
+	err = cl.decodeResponse(rsp.Body, c.Id, reply)
+	if replyErr, ok := err.(*Error); ok && replyErr.Code == E_PARSE {
+		cl.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+	} else if _, ok := err.(*MismatchError); ok {
+		cl.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+	}
+	return err
 }
 
 func ConvertError(err error) (replyError *Error) {
@@ -149,3 +252,8 @@ func ConvertError(err error) (replyError *Error) {
 	json.Unmarshal(b, replyError)
 	return
 }
+
+// isNull reports whether the raw JSON value is null or missing.
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(bytes.TrimSpace(raw)) == "null"
+}
@@ -6,8 +6,9 @@
 package jsonrpc2
 
 import (
-	"errors"
 	"encoding/json"
+	"errors"
+	"fmt"
 )
 
 type ErrorCode int
@@ -19,6 +20,8 @@ const (
 	E_BAD_PARAMS  ErrorCode = -32602
 	E_INTERNAL    ErrorCode = -32603
 	E_SERVER      ErrorCode = -32000
+	E_TIMEOUT     ErrorCode = -32001
+	E_BUSY        ErrorCode = -32002
 )
 
 var ErrNullResult = errors.New("result is null")
@@ -39,3 +42,16 @@ func (e *Error) Error() string {
 	b, _ := json.Marshal(e)
 	return string(b)
 }
+
+// MismatchError is returned by the client when a response does not match its
+// request, e.g. when a proxy returns the response to another request.
+type MismatchError struct {
+	// The mismatching member of the response, "id" or "jsonrpc".
+	Member string
+	// The expected and received values, as JSON.
+	Want, Got string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("jsonrpc2: response %s mismatch: got %s, want %s", e.Member, e.Got, e.Want)
+}
@@ -0,0 +1,56 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync/atomic"
+)
+
+// IDGenerator generates the ids of the client requests. The ids must
+// marshal to a JSON string or number.
+type IDGenerator interface {
+	NextID() interface{}
+}
+
+// IDGeneratorFunc adapts a function to the IDGenerator interface.
+type IDGeneratorFunc func() interface{}
+
+// NextID returns f().
+func (f IDGeneratorFunc) NextID() interface{} {
+	return f()
+}
+
+// NewSequentialIDGenerator returns an IDGenerator of the numbers 1, 2, 3...
+func NewSequentialIDGenerator() IDGenerator {
+	return &sequentialIDGenerator{}
+}
+
+type sequentialIDGenerator struct {
+	last uint64
+}
+
+func (g *sequentialIDGenerator) NextID() interface{} {
+	return atomic.AddUint64(&g.last, 1)
+}
+
+// UUIDGenerator generates random (version 4) UUID strings.
+var UUIDGenerator IDGenerator = IDGeneratorFunc(newUUID)
+
+func newUUID() interface{} {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// defaultIDGenerator generates the ids of the clients without an IDGenerator
+// option.
+var defaultIDGenerator = NewSequentialIDGenerator()
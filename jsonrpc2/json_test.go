@@ -7,10 +7,20 @@ package jsonrpc2
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Limard/rpcHttp"
 )
@@ -199,3 +209,539 @@ func TestDecodeNullResult(t *testing.T) {
 		t.Error("Expected result to be nil, but got:", result)
 	}
 }
+
+func serveRaw(s *rpcHttp.Server, body string) *ResponseRecorder {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestBatch(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(new(Service1), "")
+	s.SetBatchConcurrency(2)
+
+	w := serveRaw(s, `[
+		{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 2, "B": 3}, "id": 1},
+		{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 4, "B": 5}},
+		{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 4, "B": 5}, "id": "b"},
+		1
+	]`)
+	var responses []struct {
+		Id     interface{}
+		Result *Service1Response
+		Error  *Error
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("Expected a JSON array, got %q: %v", w.Body.String(), err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+	if responses[0].Id != 1.0 || responses[0].Result.Result != 6 {
+		t.Errorf("Wrong first response: %+v", responses[0])
+	}
+	if responses[1].Id != "b" || responses[1].Result.Result != 20 {
+		t.Errorf("Wrong second response: %+v", responses[1])
+	}
+	if responses[2].Id != nil || responses[2].Error == nil || responses[2].Error.Code != E_INVALID_REQ {
+		t.Errorf("Wrong third response: %+v", responses[2])
+	}
+
+	// An empty batch is a single invalid request.
+	w = serveRaw(s, `[]`)
+	var res struct {
+		Id    interface{}
+		Error *Error
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %v", w.Body.String(), err)
+	}
+	if res.Id != nil || res.Error == nil || res.Error.Code != E_INVALID_REQ {
+		t.Errorf("Wrong response to empty batch: %s", w.Body.String())
+	}
+
+	// A batch of notifications has no response.
+	w = serveRaw(s, `[{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 4, "B": 5}}]`)
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected no response, got %q", w.Body.String())
+	}
+}
+
+// PanicReply panics when encoded by the codec.
+type PanicReply struct {
+}
+
+func (PanicReply) MarshalJSON() ([]byte, error) {
+	panic("marshal")
+}
+
+type ServicePanic struct {
+}
+
+func (t *ServicePanic) Reply(r *http.Request, req *Service1Request, res *PanicReply) error {
+	return nil
+}
+
+func TestBatchPanic(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(new(Service1), "")
+	s.RegisterService(new(ServicePanic), "")
+
+	w := serveRaw(s, `[
+		{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 2, "B": 3}, "id": 1},
+		{"jsonrpc": "2.0", "method": "ServicePanic.Reply", "params": {}, "id": 2}
+	]`)
+	var responses []struct {
+		Id     interface{}
+		Result *Service1Response
+		Error  *Error
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("Expected a JSON array, got %q: %v", w.Body.String(), err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(responses))
+	}
+	if responses[0].Id != 1.0 || responses[0].Result == nil || responses[0].Result.Result != 6 {
+		t.Errorf("Wrong first response: %+v", responses[0])
+	}
+	if responses[1].Id != 2.0 || responses[1].Error == nil || responses[1].Error.Code != E_INTERNAL || responses[1].Error.Message != "rpc: internal error" {
+		t.Errorf("Wrong second response: %s", w.Body.String())
+	}
+}
+
+type Service2 struct {
+	calls chan int
+}
+
+func (t *Service2) Record(r *http.Request, req *Service1Request, res *Service1Response) error {
+	t.calls <- req.A
+	return nil
+}
+
+func TestNotification(t *testing.T) {
+	service := &Service2{calls: make(chan int, 4)}
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(new(Service1), "")
+	s.RegisterServiceWithOptions(service, "", rpcHttp.WithMethodNotifications("Record", false))
+
+	// No id member: a notification, executed without a response.
+	w := serveRaw(s, `{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 4, "B": 5}}`)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected 204 with no body, got %d %q", w.Code, w.Body.String())
+	}
+
+	// A null id is a regular request.
+	w = serveRaw(s, `{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 4, "B": 5}, "id": null}`)
+	var res struct {
+		Id     interface{}
+		Result *Service1Response
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %v", w.Body.String(), err)
+	}
+	if res.Id != nil || res.Result == nil || res.Result.Result != 20 {
+		t.Errorf("Wrong response to null id: %s", w.Body.String())
+	}
+
+	// Notifications rejected by the method are not executed.
+	w = serveRaw(s, `{"jsonrpc": "2.0", "method": "Service2.Record", "params": {"A": 1}}`)
+	if w.Code != http.StatusBadRequest || w.Body.Len() != 0 {
+		t.Errorf("Expected 400 with no body, got %d %q", w.Code, w.Body.String())
+	}
+	if len(service.calls) != 0 {
+		t.Errorf("Expected the rejected notification not to be executed")
+	}
+	w = serveRaw(s, `{"jsonrpc": "2.0", "method": "Service2.Record", "params": {"A": 2}, "id": 1}`)
+	if w.Code != http.StatusOK || <-service.calls != 2 {
+		t.Errorf("Expected the request to be executed, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAsyncNotification(t *testing.T) {
+	service := &Service2{calls: make(chan int)}
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(service, "")
+	s.SetAsyncNotifications(true)
+
+	// The call blocks until the test reads it, after the response is sent.
+	w := serveRaw(s, `{"jsonrpc": "2.0", "method": "Service2.Record", "params": {"A": 3}}`)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d %q", w.Code, w.Body.String())
+	}
+	select {
+	case a := <-service.calls:
+		if a != 3 {
+			t.Errorf("Wrong notification params: got %d, want 3", a)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the notification to be executed")
+	}
+}
+
+type Service3 struct {
+}
+
+func (t *Service3) Greet(name string, times int, res *string) error {
+	*res = strings.Repeat("hello "+name+" ", times)
+	return nil
+}
+
+func (t *Service3) Sum(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A + req.B
+	return nil
+}
+
+type Service3Request struct {
+	Data map[string]interface{}
+}
+
+func (t *Service3) Echo(r *http.Request, req *Service3Request, res *Service3Request) error {
+	*res = *req
+	return nil
+}
+
+func TestPositionalParams(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	if err := s.RegisterServiceWithOptions(new(Service3), "", rpcHttp.WithMethodParams("Greet", "name", "times")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		params string
+		result interface{}
+		code   ErrorCode
+	}{
+		{"Service3.Greet", `["alice", 2]`, "hello alice hello alice ", 0},
+		{"Service3.Greet", `{"name": "bob", "times": 1}`, "hello bob ", 0},
+		{"Service3.Greet", `["alice"]`, nil, E_BAD_PARAMS},
+		{"Service3.Greet", `["alice", "2"]`, nil, E_BAD_PARAMS},
+		{"Service3.Greet", `{"name": "bob", "times": "1"}`, nil, E_BAD_PARAMS},
+		// The args of the other methods are not read by position.
+		{"Service3.Sum", `[{"A": 2, "B": 4}]`, map[string]interface{}{"Result": 6.0}, 0},
+		{"Service3.Sum", `{"A": 1, "B": 1}`, map[string]interface{}{"Result": 2.0}, 0},
+		{"Service3.Sum", `[2, 3]`, nil, E_INVALID_REQ},
+		{"Service3.Echo", `[{"Data": {"a": 1}}]`, map[string]interface{}{"Data": map[string]interface{}{"a": 1.0}}, 0},
+	}
+	for _, test := range tests {
+		w := serveRaw(s, `{"jsonrpc": "2.0", "method": "`+test.method+`", "params": `+test.params+`, "id": 1}`)
+		var res struct {
+			Result interface{}
+			Error  *Error
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Expected a JSON object, got %q: %v", w.Body.String(), err)
+		}
+		if test.code != 0 {
+			if res.Error == nil || res.Error.Code != test.code {
+				t.Errorf("%s %s: expected error %d, got %s", test.method, test.params, test.code, w.Body.String())
+			}
+			continue
+		}
+		if res.Error != nil || !reflect.DeepEqual(res.Result, test.result) {
+			t.Errorf("%s %s: expected %v, got %s", test.method, test.params, test.result, w.Body.String())
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCustomCodec(&rpcHttp.CompressionSelector{}), "application/json")
+	s.RegisterService(new(Service1), "")
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		r, _ := http.NewRequest("POST", "http://localhost:8080/", bytes.NewBufferString(
+			`{"jsonrpc": "2.0", "method": "Service1.Multiply", "params": {"A": 4, "B": 5}, "id": 1}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept-Encoding", encoding)
+		w := NewRecorder()
+		s.ServeHTTP(w, r)
+
+		if got := w.HeaderMap.Get("Content-Encoding"); got != encoding {
+			t.Fatalf("Expected Content-Encoding %q, got %q", encoding, got)
+		}
+		var reader io.Reader
+		if encoding == "gzip" {
+			gr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader = gr
+		} else {
+			reader = flate.NewReader(w.Body)
+		}
+		var res Service1Response
+		if err := decodeClientResponse(reader, &res); err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if res.Result != 20 {
+			t.Errorf("%s: wrong response: got %v, want 20", encoding, res.Result)
+		}
+	}
+}
+
+type Service4Request struct {
+	User struct {
+		Name string `json:"name"`
+		Tags []int  `json:"tags"`
+	} `json:"user"`
+}
+
+type Service4 struct {
+}
+
+func (t *Service4) Save(r *http.Request, req *Service4Request, res *Service1Response) error {
+	res.Result = len(req.User.Tags)
+	return nil
+}
+
+func TestStrictDecoding(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(WithStrictDecoding()), "application/json")
+	s.RegisterService(new(Service1), "")
+	s.RegisterServiceWithOptions(new(Service3), "", rpcHttp.WithPositionalParams())
+	s.RegisterService(new(Service4), "")
+
+	tests := []struct {
+		method string
+		params string
+		path   string
+	}{
+		{"Service1.Multiply", `{"A": 2, "B": 3}`, ""},
+		{"Service1.Multiply", `{"A": 2, "C": 3}`, "params.C"},
+		{"Service1.Multiply", `[{"A": 2, "B": 3}]`, "params"},
+		{"Service1.Multiply", `[2, 3]`, "params"},
+		{"Service1.Multiply", `2`, "params"},
+		{"Service3.Greet", `["alice", 2]`, ""},
+		{"Service3.Greet", `["alice", "2"]`, "params[1]"},
+		{"Service3.Greet", `["alice"]`, "params"},
+		{"Service4.Save", `{"user": {"name": "alice", "tags": [1, 2]}}`, ""},
+		{"Service4.Save", `{"user": {"nmae": "alice"}}`, "params.user.nmae"},
+		{"Service4.Save", `{"user": {"name": "alice", "tags": [1, "2"]}}`, "params.user.tags[1]"},
+	}
+	for _, test := range tests {
+		w := serveRaw(s, `{"jsonrpc": "2.0", "method": "`+test.method+`", "params": `+test.params+`, "id": 1}`)
+		var res struct {
+			Error *struct {
+				Code ErrorCode
+				Data struct {
+					Path string
+				}
+			}
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Expected a JSON object, got %q: %v", w.Body.String(), err)
+		}
+		if test.path == "" {
+			if res.Error != nil {
+				t.Errorf("%s: unexpected error %s", test.params, w.Body.String())
+			}
+			continue
+		}
+		if res.Error == nil || res.Error.Code != E_BAD_PARAMS || res.Error.Data.Path != test.path {
+			t.Errorf("%s: expected error at %q, got %s", test.params, test.path, w.Body.String())
+		}
+	}
+
+	// Trailing data after the params.
+	if path, err := (&CodecRequest{strict: true}).readStrictParams(json.RawMessage(`{"A": 1} {}`), new(Service1Request), false); err == nil || path != "params" {
+		t.Errorf("Expected an error on trailing data, got %q, %v", path, err)
+	}
+}
+
+func TestClientResponseMismatch(t *testing.T) {
+	tests := []struct {
+		id       interface{}
+		response string
+		member   string
+	}{
+		{uint64(7), `{"jsonrpc": "2.0", "id": 7, "result": {"Result": 1}}`, ""},
+		{"a-b", `{"jsonrpc": "2.0", "id": "a-b", "result": {"Result": 1}}`, ""},
+		{uint64(7), `{"jsonrpc": "2.0", "id": 8, "result": {"Result": 1}}`, "id"},
+		{uint64(7), `{"jsonrpc": "2.0", "id": "7", "result": {"Result": 1}}`, "id"},
+		{uint64(7), `{"jsonrpc": "1.0", "id": 7, "result": {"Result": 1}}`, "jsonrpc"},
+		{uint64(7), `{"id": 7, "result": {"Result": 1}}`, "jsonrpc"},
+	}
+	for _, test := range tests {
+		var res Service1Response
+		err := NewClient().decodeResponse(bytes.NewBufferString(test.response), test.id, &res)
+		mismatch, ok := err.(*MismatchError)
+		if test.member == "" {
+			if err != nil || res.Result != 1 {
+				t.Errorf("%s: unexpected error %v", test.response, err)
+			}
+		} else if !ok || mismatch.Member != test.member {
+			t.Errorf("%s: expected %s mismatch, got %v", test.response, test.member, err)
+		}
+	}
+
+	// An error response to an unreadable request has a null id.
+	err := NewClient().decodeResponse(bytes.NewBufferString(`{"jsonrpc": "2.0", "id": null, "error": {"code": -32700, "message": "parse error"}}`), uint64(7), nil)
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Code != E_PARSE {
+		t.Errorf("Expected the parse error, got %v", err)
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	gen := NewSequentialIDGenerator()
+	if a, b := gen.NextID(), gen.NextID(); a != uint64(1) || b != uint64(2) {
+		t.Errorf("Wrong sequential ids: %v, %v", a, b)
+	}
+	a, b := UUIDGenerator.NextID().(string), UUIDGenerator.NextID().(string)
+	if len(a) != 36 || a[14] != '4' || a == b {
+		t.Errorf("Wrong UUIDs: %s, %s", a, b)
+	}
+
+	client := NewClient(WithIDGenerator(IDGeneratorFunc(func() interface{} { return "fixed" })))
+	if id := client.newRequest("Service1.Multiply", nil).Id; id != "fixed" {
+		t.Errorf("Wrong id: %v", id)
+	}
+	// Other clients keep the default.
+	if id := NewClient().newRequest("Service1.Multiply", nil).Id; id == "fixed" {
+		t.Errorf("Wrong default id: %v", id)
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("oops"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewClient(WithHTTPClient(server.Client()), WithClientLogger(rpcHttp.NewStdLogger(log.New(&buf, "", 0))))
+	var res Service1Response
+	if err := client.Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err == nil {
+		t.Errorf("Expected a parse error")
+	}
+	if !strings.HasPrefix(buf.String(), "level=warn msg=decodeClientResponse method=Service1.Multiply") {
+		t.Errorf("Wrong log: %q", buf.String())
+	}
+	// The package functions log nothing.
+	buf.Reset()
+	Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res)
+	if buf.Len() != 0 {
+		t.Errorf("Unexpected log: %q", buf.String())
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), "application/json")
+	s.RegisterService(new(Service1), "")
+
+	w := serveRaw(s, `{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`)
+	var res struct {
+		Result *rpcHttp.OpenRPCDocument
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Result == nil {
+		t.Fatalf("Expected an OpenRPC document, got %q: %v", w.Body.String(), err)
+	}
+	if res.Result.OpenRPC != rpcHttp.OpenRPCVersion || len(res.Result.Methods) != 2 {
+		t.Errorf("Wrong OpenRPC document: %s", w.Body.String())
+	}
+	if _, ok := res.Result.Components.Schemas["Service1Response"]; !ok {
+		t.Errorf("Expected the Service1Response schema: %s", w.Body.String())
+	}
+
+	// The notifications get no response.
+	w = serveRaw(s, `{"jsonrpc": "2.0", "method": "rpc.discover"}`)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected no response to a notification, got %d %s", w.Code, w.Body.String())
+	}
+
+	// The interceptors see the calls.
+	s.Use(func(ctx context.Context, call *rpcHttp.CallInfo, next rpcHttp.Handler) (int, error, interface{}) {
+		if call.Method == rpcHttp.DiscoverMethod {
+			return int(E_INVALID_REQ), errors.New("unauthorized"), nil
+		}
+		return next(ctx, call)
+	})
+	w = serveRaw(s, `{"jsonrpc": "2.0", "method": "rpc.discover", "id": 1}`)
+	if want := `{"jsonrpc":"2.0","error":{"code":-32600,"message":"unauthorized","data":null},"id":1}`; w.Body.String() != want {
+		t.Errorf("Wrong response: got %s, want %s", w.Body.String(), want)
+	}
+}
+
+type Service5Request struct {
+	Value interface{}
+}
+
+type Service5 struct {
+}
+
+func (t *Service5) Echo(r *http.Request, req *Service5Request, res *Service5Request) error {
+	if _, ok := req.Value.(json.Number); !ok {
+		return fmt.Errorf("value is a %T", req.Value)
+	}
+	res.Value = req.Value
+	return nil
+}
+
+func TestUseNumber(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(WithUseNumber()), "application/json")
+	s.RegisterService(new(Service5), "")
+
+	w := serveRaw(s, `{"jsonrpc": "2.0", "method": "Service5.Echo", "params": {"Value": 9007199254740993}, "id": 9007199254740993}`)
+	if want := `{"jsonrpc":"2.0","result":{"Value":9007199254740993},"id":9007199254740993}`; w.Body.String() != want {
+		t.Errorf("Wrong response: got %s, want %s", w.Body.String(), want)
+	}
+
+	client := NewClient(WithClientUseNumber())
+	var res Service5Request
+	if err := client.decodeResponse(w.Body, nil, &res); err != nil || res.Value != json.Number("9007199254740993") {
+		t.Errorf("Wrong result: %#v, %v", res.Value, err)
+	}
+	err := client.decodeResponse(bytes.NewBufferString(`{"jsonrpc": "2.0", "id": 1, "error": {"code": 1, "message": "m", "data": 9007199254740993}}`), nil, &res)
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Data != json.Number("9007199254740993") {
+		t.Errorf("Wrong error data: %v", err)
+	}
+	// The default client decodes float64.
+	err = decodeClientResponse(bytes.NewBufferString(`{"jsonrpc": "2.0", "id": 1, "result": {"Value": 1}}`), &res)
+	if err != nil || res.Value != float64(1) {
+		t.Errorf("Wrong default result: %#v, %v", res.Value, err)
+	}
+}
+
+type countingUnmarshaler struct {
+	calls int
+}
+
+func (u *countingUnmarshaler) Unmarshal(data []byte, v interface{}) error {
+	u.calls++
+	return json.Unmarshal(data, v)
+}
+
+func TestMarshaler(t *testing.T) {
+	marshaler := NewJSONMarshaler()
+	marshaler.SetEscapeHTML(false)
+	marshaler.SetIndent("", " ")
+	unmarshaler := new(countingUnmarshaler)
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(WithMarshaler(marshaler), WithUnmarshaler(unmarshaler)), "application/json")
+	s.RegisterServiceWithOptions(new(Service3), "", rpcHttp.WithPositionalParams())
+
+	w := serveRaw(s, `{"jsonrpc": "2.0", "method": "Service3.Greet", "params": ["<b>", 1], "id": 1}`)
+	if want := "{\n \"jsonrpc\": \"2.0\",\n \"result\": \"hello <b> \",\n \"id\": 1\n}"; w.Body.String() != want {
+		t.Errorf("Wrong response: got %q, want %q", w.Body.String(), want)
+	}
+	if unmarshaler.calls == 0 {
+		t.Error("Expected the request to be decoded by the custom unmarshaler")
+	}
+
+	// The default marshaler escapes HTML as json.Marshal.
+	b, err := NewJSONMarshaler().Marshal("<b>")
+	if err != nil || string(b) != `"\u003cb\u003e"` {
+		t.Errorf("Wrong default encoding: %s, %v", b, err)
+	}
+}
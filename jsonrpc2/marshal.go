@@ -0,0 +1,69 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Marshaler encodes the responses of a Codec as JSON.
+type Marshaler interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// Unmarshaler decodes the requests of a Codec from JSON. It must support
+// json.RawMessage destinations, as encoding/json does.
+type Unmarshaler interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONMarshaler is the Marshaler and Unmarshaler of encoding/json, the
+// default of a Codec.
+type JSONMarshaler struct {
+	escapeHTML     bool
+	prefix, indent string
+}
+
+// NewJSONMarshaler returns a JSONMarshaler that escapes HTML and does not
+// indent, as json.Marshal.
+func NewJSONMarshaler() *JSONMarshaler {
+	return &JSONMarshaler{escapeHTML: true}
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings, see json.Encoder.SetEscapeHTML.
+func (m *JSONMarshaler) SetEscapeHTML(on bool) {
+	m.escapeHTML = on
+}
+
+// SetIndent makes the marshaler indent the responses, see
+// json.Encoder.SetIndent. Calling SetIndent("", "") disables indentation.
+func (m *JSONMarshaler) SetIndent(prefix, indent string) {
+	m.prefix = prefix
+	m.indent = indent
+}
+
+// Marshal returns the JSON encoding of v.
+func (m *JSONMarshaler) Marshal(v interface{}) ([]byte, error) {
+	if m.escapeHTML && m.prefix == "" && m.indent == "" {
+		return json.Marshal(v)
+	}
+	var buffer bytes.Buffer
+	enc := json.NewEncoder(&buffer)
+	enc.SetEscapeHTML(m.escapeHTML)
+	enc.SetIndent(m.prefix, m.indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode terminates the value with a newline.
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// Unmarshal parses the JSON data and stores the result in v.
+func (m *JSONMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -6,9 +6,12 @@
 package jsonrpc2
 
 import (
+	"bytes"
 	"encoding/json"
-	"log"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"reflect"
 
 	"github.com/Limard/rpcHttp"
 )
@@ -33,8 +36,9 @@ type serverRequest struct {
 
 	// The request id. MUST be a string, number or null.
 	// Our implementation will not do type checking for id.
-	// It will be copied as it is.
-	Id *json.RawMessage `json:"id"`
+	// It will be copied as it is. It is empty if the member is absent, which
+	// makes the request a notification, and "null" if the id is null.
+	Id json.RawMessage `json:"id,omitempty"`
 }
 
 // serverResponse represents a JSON-RPC response returned by the server.
@@ -53,7 +57,7 @@ type serverResponse struct {
 	Error *Error `json:"error,omitempty"`
 
 	// This must be the same id as the request it is responding to.
-	Id *json.RawMessage `json:"id"`
+	Id json.RawMessage `json:"id"`
 }
 
 // ----------------------------------------------------------------------------
@@ -61,23 +65,81 @@ type serverResponse struct {
 // ----------------------------------------------------------------------------
 
 // NewcustomCodec returns a new JSON Codec based on passed encoder selector.
-func NewCustomCodec(encSel rpcHttp.EncoderSelector) *Codec {
-	return &Codec{encSel: encSel}
+func NewCustomCodec(encSel rpcHttp.EncoderSelector, opts ...CodecOption) *Codec {
+	c := &Codec{encSel: encSel, logger: rpcHttp.NopLogger, marshaler: NewJSONMarshaler()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // NewCodec returns a new JSON Codec.
-func NewCodec() *Codec {
-	return NewCustomCodec(rpcHttp.DefaultEncoderSelector)
+func NewCodec(opts ...CodecOption) *Codec {
+	return NewCustomCodec(rpcHttp.DefaultEncoderSelector, opts...)
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithLogger sets the logger of the codec.
+func WithLogger(logger rpcHttp.Logger) CodecOption {
+	return func(c *Codec) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithStrictDecoding makes the codec reject params with unknown fields,
+// trailing data, or params that are neither an object nor an array. The
+// E_BAD_PARAMS error data then holds the JSON path of the offending value.
+func WithStrictDecoding() CodecOption {
+	return func(c *Codec) {
+		c.strict = true
+	}
+}
+
+// WithUseNumber makes the codec decode the numbers of the params held in
+// interface values as json.Number instead of float64, so that large integers
+// keep their precision.
+func WithUseNumber() CodecOption {
+	return func(c *Codec) {
+		c.useNumber = true
+	}
+}
+
+// WithMarshaler sets the marshaler encoding the responses of the codec,
+// a JSONMarshaler by default.
+func WithMarshaler(marshaler Marshaler) CodecOption {
+	return func(c *Codec) {
+		if marshaler != nil {
+			c.marshaler = marshaler
+		}
+	}
+}
+
+// WithUnmarshaler sets the unmarshaler decoding the requests of the codec,
+// encoding/json by default. WithStrictDecoding and WithUseNumber only apply
+// to the default, a custom unmarshaler is configured on its own.
+func WithUnmarshaler(unmarshaler Unmarshaler) CodecOption {
+	return func(c *Codec) {
+		c.unmarshaler = unmarshaler
+	}
 }
 
 // Codec creates a CodecRequest to process each request.
 type Codec struct {
-	encSel rpcHttp.EncoderSelector
+	encSel      rpcHttp.EncoderSelector
+	logger      rpcHttp.Logger
+	strict      bool
+	useNumber   bool
+	marshaler   Marshaler
+	unmarshaler Unmarshaler
 }
 
 // NewRequest returns a CodecRequest.
 func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
-	return newCodecRequest(r, c.encSel.Select(r))
+	return newCodecRequest(r, c.encSel.Select(r), c)
 }
 
 // ----------------------------------------------------------------------------
@@ -85,33 +147,150 @@ func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
 // ----------------------------------------------------------------------------
 
 // newCodecRequest returns a new CodecRequest.
-func newCodecRequest(r *http.Request, encoder rpcHttp.Encoder) rpcHttp.CodecRequest {
+func newCodecRequest(r *http.Request, encoder rpcHttp.Encoder, codec *Codec) rpcHttp.CodecRequest {
 	// Decode the request body and check if RPC method is valid.
-	req := new(serverRequest)
-	err := json.NewDecoder(r.Body).Decode(req)
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	c := &CodecRequest{
+		request:   new(serverRequest),
+		encoder:   encoder,
+		logger:    codec.logger,
+		strict:    codec.strict,
+		useNumber: codec.useNumber,
+		remote:    r.RemoteAddr,
+
+		marshaler:   codec.marshaler,
+		unmarshaler: codec.unmarshaler,
+	}
 	if err != nil {
-		err = &Error{
+		c.err = &Error{
+			Code:    E_PARSE,
+			Message: err.Error(),
+		}
+		c.invalid = true
+		return c
+	}
+	body = bytes.TrimLeft(body, " \t\r\n")
+	if len(body) == 0 || body[0] != '[' {
+		c.parse(body)
+		return c
+	}
+
+	// Batch request.
+	var batch []json.RawMessage
+	if err := c.decode(body, &batch); err != nil {
+		c.err = &Error{
 			Code:    E_PARSE,
 			Message: err.Error(),
+		}
+		c.invalid = true
+		return c
+	}
+	if len(batch) == 0 {
+		c.err = &Error{
+			Code:    E_INVALID_REQ,
+			Message: "empty batch",
+		}
+		c.invalid = true
+		return c
+	}
+	c.isBatch = true
+	c.batch = make([]rpcHttp.CodecRequest, len(batch))
+	for i, raw := range batch {
+		req := &CodecRequest{
+			request:   new(serverRequest),
+			encoder:   rpcHttp.DefaultEncoder,
+			logger:    codec.logger,
+			strict:    codec.strict,
+			useNumber: codec.useNumber,
+			remote:    r.RemoteAddr,
+
+			marshaler:   codec.marshaler,
+			unmarshaler: codec.unmarshaler,
+		}
+		req.parse(raw)
+		c.batch[i] = req
+	}
+	return c
+}
+
+// parse decodes a single request from data.
+func (c *CodecRequest) parse(data []byte) {
+	req := c.request
+	err := c.decode(data, req)
+	if err != nil {
+		code := E_PARSE
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			// Valid JSON, but not a request object.
+			code = E_INVALID_REQ
+		}
+		c.err = &Error{
+			Code:    code,
+			Message: err.Error(),
 			Data:    req,
 		}
+		c.invalid = true
 	}
-	if req.Version != Version {
-		err = &Error{
+	if err == nil && req.Version != Version {
+		c.err = &Error{
 			Code:    E_INVALID_REQ,
 			Message: "jsonrpc must be " + Version,
 			Data:    req,
 		}
+		c.invalid = true
 	}
-	r.Body.Close()
-	return &CodecRequest{request: req, err: err, encoder: encoder}
 }
 
 // CodecRequest decodes and encodes a single request.
 type CodecRequest struct {
-	request *serverRequest
-	err     error
-	encoder rpcHttp.Encoder
+	request   *serverRequest
+	err       error
+	invalid   bool // the request could not be read, answer with a null id
+	isBatch   bool
+	batch     []rpcHttp.CodecRequest
+	encoder   rpcHttp.Encoder
+	logger    rpcHttp.Logger
+	strict    bool // strict decoding of the params
+	useNumber bool // decode numbers as json.Number
+	remote    string
+
+	marshaler   Marshaler
+	unmarshaler Unmarshaler // nil for encoding/json
+}
+
+// Batch returns the requests of a batch request, or false if the request is
+// not a batch.
+func (c *CodecRequest) Batch() ([]rpcHttp.CodecRequest, bool) {
+	return c.batch, c.isBatch
+}
+
+// WriteBatchResponse writes the responses of the batch requests as a JSON
+// array. Empty responses, such as the ones of notifications, are omitted.
+func (c *CodecRequest) WriteBatchResponse(w http.ResponseWriter, responses [][]byte) {
+	var buffer bytes.Buffer
+	for _, response := range responses {
+		if len(response) == 0 {
+			continue
+		}
+		if buffer.Len() == 0 {
+			buffer.WriteByte('[')
+		} else {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(response)
+	}
+	if buffer.Len() == 0 {
+		// Only notifications, nothing to return.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	buffer.WriteByte(']')
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := rpcHttp.EncodeResponse(w, c.encoder, buffer.Bytes()); err != nil {
+		c.logger.Warn("write response", "codec", ContentType, "remote", c.remote, "err", err)
+	}
 }
 
 // Method returns the RPC method for the current request.
@@ -124,48 +303,180 @@ func (c *CodecRequest) Method() (string, error) {
 	return "", c.err
 }
 
+// RequestID returns the raw JSON id of the current request.
+func (c *CodecRequest) RequestID() interface{} {
+	if len(c.request.Id) == 0 {
+		return nil
+	}
+	return c.request.Id
+}
+
+// IsNotification reports whether the request is a notification, a request
+// without an id member. A request with a null id is not a notification.
+func (c *CodecRequest) IsNotification() bool {
+	return len(c.request.Id) == 0 && !c.invalid
+}
+
 // ReadRequest fills the request object for the RPC method.
 //
 // ReadRequest parses request parameters in two supported forms in
 // accordance with http://www.jsonrpc.org/specification#parameter_structures
 //
 // by-position: params MUST be an Array, containing the
-// values in the Server expected order.
+// values in the Server expected order. An Array holding a single Object
+// is read as the whole args.
 //
 // by-name: params MUST be an Object, with member names
 // that match the Server expected parameter names. The
 // absence of expected names MAY result in an error being
 // generated. The names MUST match exactly, including
 // case, to the method's expected parameters.
+//
+// In strict mode, see WithStrictDecoding, the single Object form is not
+// accepted and unknown fields are errors.
 func (c *CodecRequest) ReadRequest(args interface{}) error {
-	if c.err == nil && c.request.Params != nil {
+	return c.readParams(args, false)
+}
+
+// ReadPositionalRequest implements rpcHttp.PositionalCodecRequest: the
+// values of a by-position Array fill the parameters of the method in order,
+// and a mismatch of their number or types is an E_BAD_PARAMS error.
+func (c *CodecRequest) ReadPositionalRequest(args interface{}) error {
+	return c.readParams(args, true)
+}
+
+// readParams unmarshals the params into args, by position into the
+// parameters of the method if positional.
+func (c *CodecRequest) readParams(args interface{}, positional bool) error {
+	if c.err != nil || c.request.Params == nil {
 		// Note: if c.request.Params is nil it's not an error, it's an optional member.
-		// JSON params structured object. Unmarshal to the args object.
-		if err := json.Unmarshal(*c.request.Params, args); err != nil {
-			// Clearly JSON params is not a structured object,
-			// fallback and attempt an unmarshal with JSON params as
-			// array value and RPC params is struct. Unmarshal into
-			// array containing the request struct.
-			params := [1]interface{}{args}
-			if err = json.Unmarshal(*c.request.Params, &params); err != nil {
-				log.Printf("ERROR: %s", string(*c.request.Params))
-				c.err = &Error{
-					Code:    E_INVALID_REQ,
-					Message: err.Error(),
-					Data:    c.request.Params,
-				}
+		return c.err
+	}
+	params := *c.request.Params
+	if c.strict {
+		if path, err := c.readStrictParams(params, args, positional); err != nil {
+			c.logger.Warn("invalid params", c.logFields("params", string(params), "path", path, "err", err)...)
+			c.err = &Error{
+				Code:    E_BAD_PARAMS,
+				Message: err.Error(),
+				Data:    map[string]string{"path": path},
 			}
 		}
+		return c.err
+	}
+	var err error
+	if positional && isArray(params) {
+		// JSON params array, the parameters of the method by position.
+		err = c.readPositionalParams(params, args)
+	} else if err = c.unmarshal(params, args); err != nil {
+		// JSON params structured object. Unmarshal to the args object.
+		// Clearly JSON params is not a structured object,
+		// fallback and attempt an unmarshal with JSON params as
+		// array value and RPC params is struct. Unmarshal into
+		// array containing the request struct.
+		array := [1]interface{}{args}
+		err = c.unmarshal(params, &array)
+	}
+	if err != nil {
+		c.logger.Warn("invalid params", c.logFields("params", string(params), "err", err)...)
+		code := E_INVALID_REQ
+		if positional {
+			code = E_BAD_PARAMS
+		}
+		c.err = &Error{
+			Code:    code,
+			Message: err.Error(),
+			Data:    c.request.Params,
+		}
 	}
 	return c.err
 }
 
+// decode decodes the JSON data of the request envelope into v.
+func (c *CodecRequest) decode(data []byte, v interface{}) error {
+	if c.unmarshaler != nil {
+		return c.unmarshaler.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unmarshal decodes params data into v according to the codec options.
+func (c *CodecRequest) unmarshal(data []byte, v interface{}) error {
+	if c.unmarshaler != nil {
+		return c.unmarshaler.Unmarshal(data, v)
+	}
+	return unmarshal(data, v, c.strict, c.useNumber)
+}
+
+// readPositionalParams unmarshals the elements of the JSON array data into
+// the parameters of the method, the exported fields of the struct pointed to
+// by args, in order.
+func (c *CodecRequest) readPositionalParams(data []byte, args interface{}) error {
+	var values []json.RawMessage
+	if err := c.decode(data, &values); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(args).Elem()
+	fields := positionalFields(v.Type())
+	// A single object holding the whole args, as sent by older clients.
+	if len(values) == 1 && isObject(values[0]) &&
+		(len(fields) != 1 || !acceptsObject(v.Type().Field(fields[0]).Type)) {
+		return c.unmarshal(values[0], args)
+	}
+	if len(values) != len(fields) {
+		return fmt.Errorf("wrong number of params: got %d, want %d", len(values), len(fields))
+	}
+	for i, value := range values {
+		field := v.Field(fields[i])
+		if err := c.unmarshal(value, field.Addr().Interface()); err != nil {
+			return fmt.Errorf("param %d (%s): %v", i, v.Type().Field(fields[i]).Name, err)
+		}
+	}
+	return nil
+}
+
+// positionalFields returns the indexes of the fields of struct type t
+// decoded by position: the exported fields not ignored by encoding/json.
+func positionalFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("json") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+// acceptsObject reports whether a JSON object can be decoded into type t.
+func acceptsObject(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func isArray(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
+func isObject(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '{'
+}
+
 // WriteResponse encodes the response and writes it to the ResponseWriter.
 func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}) {
 	res := &serverResponse{
 		Version: Version,
 		Result:  reply,
-		Id:      c.request.Id,
+		Id:      c.responseID(),
 	}
 	c.writeServerResponse(w, res)
 }
@@ -182,36 +493,46 @@ func (c *CodecRequest) WriteErrorResponse(w http.ResponseWriter, code int, err e
 	res := &serverResponse{
 		Version: Version,
 		Error:   jsonErr,
-		Id:      c.request.Id,
+		Id:      c.responseID(),
 	}
 	c.writeServerResponse(w, res)
 }
 
-func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, res *serverResponse) {
-	// Id is null for notifications and they don't have a response.
-	if c.request.Id != nil {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-		var buffer []byte
-		var err error
-		//buffer, err = json.Marshal(c.request)
-		// log.Println("Request:", string(buffer))
-
-		buffer, err = json.Marshal(res)
-		// log.Println("Response:", string(buffer))
+// responseID returns the id of the response, null if the request has none.
+func (c *CodecRequest) responseID() json.RawMessage {
+	if len(c.request.Id) == 0 {
+		return null
+	}
+	return c.request.Id
+}
 
-		w.Write(buffer)
-		// encoder := json.NewEncoder(c.encoder.Encode(w))
-		// err = encoder.Encode(res)
+func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, res *serverResponse) {
+	// Notifications don't have a response.
+	if c.IsNotification() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-		// Not sure in which case will this happen. But seems harmless.
-		if err != nil {
-			log.Println("json Encode:", err.Error())
-			rpcHttp.WriteError(w, 400, err.Error())
-		}
+	buffer, err := c.marshaler.Marshal(res)
+	// Not sure in which case will this happen. But seems harmless.
+	if err != nil {
+		c.logger.Error("json Encode", c.logFields("err", err)...)
+		rpcHttp.WriteError(w, 400, err.Error())
+		return
 	}
+	if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+		c.logger.Warn("write response", c.logFields("err", err)...)
+	}
+}
+
+// logFields returns the log fields identifying the request followed by
+// keyvals.
+func (c *CodecRequest) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"method", c.request.Method, "codec", ContentType, "id", c.RequestID(), "remote", c.remote}
+	return append(fields, keyvals...)
 }
 
 type EmptyResponse struct {
@@ -0,0 +1,196 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var typeOfUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// readStrictParams unmarshals params into args, by position into the
+// parameters of the method if positional, rejecting unknown fields and
+// trailing data. On error it also returns the JSON path of the offending
+// value, as in "params.user.name" or "params[1]".
+func (c *CodecRequest) readStrictParams(params json.RawMessage, args interface{}, positional bool) (string, error) {
+	if !isObject(params) && !isArray(params) {
+		return "params", errors.New("params must be an object or an array")
+	}
+	if !positional || !isArray(params) {
+		if err := c.unmarshal(params, args); err != nil {
+			return errorPath(params, reflect.TypeOf(args), "params"), err
+		}
+		return "", nil
+	}
+
+	// JSON params array, the parameters of the method by position.
+	var values []json.RawMessage
+	if err := c.unmarshal(params, &values); err != nil {
+		return "params", err
+	}
+	v := reflect.ValueOf(args).Elem()
+	fields := positionalFields(v.Type())
+	if len(values) != len(fields) {
+		return "params", fmt.Errorf("wrong number of params: got %d, want %d", len(values), len(fields))
+	}
+	for i, value := range values {
+		field := v.Field(fields[i])
+		if err := c.unmarshal(value, field.Addr().Interface()); err != nil {
+			return errorPath(value, field.Type(), fmt.Sprintf("params[%d]", i)), err
+		}
+	}
+	return "", nil
+}
+
+// strictUnmarshal is json.Unmarshal, rejecting unknown object fields.
+func strictUnmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v, true, false)
+}
+
+// unmarshal is json.Unmarshal, rejecting unknown object fields if strict and
+// decoding the numbers of interface values as json.Number if useNumber.
+func unmarshal(data []byte, v interface{}, strict, useNumber bool) error {
+	if !strict && !useNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if useNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("json: trailing data after top-level value")
+	}
+	return nil
+}
+
+// errorPath returns the path of the first value of data, read at path, that
+// cannot be decoded strictly into type t. It returns path itself if the
+// error is not in a member or an element.
+func errorPath(data []byte, t reflect.Type, path string) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(typeOfUnmarshaler) {
+		return path
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		members, keys, ok := objectMembers(data)
+		if !ok {
+			return path
+		}
+		for _, key := range keys {
+			field, ok := fieldByName(t, key)
+			if !ok {
+				return path + "." + key
+			}
+			if strictUnmarshal(members[key], reflect.New(field.Type).Interface()) != nil {
+				return errorPath(members[key], field.Type, path+"."+key)
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return path
+		}
+		members, keys, ok := objectMembers(data)
+		if !ok {
+			return path
+		}
+		for _, key := range keys {
+			if strictUnmarshal(members[key], reflect.New(t.Elem()).Interface()) != nil {
+				return errorPath(members[key], t.Elem(), path+"."+key)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Base64 encoded bytes.
+			return path
+		}
+		var values []json.RawMessage
+		if json.Unmarshal(data, &values) != nil {
+			return path
+		}
+		for i, value := range values {
+			if strictUnmarshal(value, reflect.New(t.Elem()).Interface()) != nil {
+				return errorPath(value, t.Elem(), path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+	return path
+}
+
+// objectMembers decodes the members of a JSON object, with their keys sorted.
+func objectMembers(data []byte) (map[string]json.RawMessage, []string, bool) {
+	var members map[string]json.RawMessage
+	if json.Unmarshal(data, &members) != nil || members == nil {
+		return nil, nil, false
+	}
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return members, keys, true
+}
+
+// fieldByName returns the field of struct type t that encoding/json decodes
+// the object member name into, preferring an exact match to a case-insensitive
+// one.
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	var fold *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		fieldName := field.Name
+		if idx := strings.Index(tag, ","); idx != -1 {
+			tag = tag[:idx]
+		}
+		if tag != "" {
+			fieldName = tag
+		}
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && tag == "" && embedded.Kind() == reflect.Struct {
+			// Promoted fields of an embedded struct.
+			if f, ok := fieldByName(embedded, name); ok {
+				return f, true
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if fieldName == name {
+			return field, true
+		}
+		if fold == nil && strings.EqualFold(fieldName, name) {
+			fold = &field
+		}
+	}
+	if fold != nil {
+		return *fold, true
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,119 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+)
+
+// ErrServerBusy is returned when a call is rejected because the concurrency
+// limit is reached and the wait queue is full.
+var ErrServerBusy = errors.New("rpc: server busy")
+
+// limiter bounds the number of calls in flight. Calls over the limit wait in
+// a queue of bounded length. A nil limiter has no limit.
+type limiter struct {
+	slots   chan struct{}
+	queue   int32
+	waiting int32
+}
+
+// newLimiter returns a limiter allowing maxInFlight concurrent calls and
+// queue waiting calls, or nil if maxInFlight is not positive.
+func newLimiter(maxInFlight, queue int) *limiter {
+	if maxInFlight <= 0 {
+		return nil
+	}
+	return &limiter{
+		slots: make(chan struct{}, maxInFlight),
+		queue: int32(queue),
+	}
+}
+
+// acquire takes a slot, waiting in the queue if needed. It returns
+// ErrServerBusy if the queue is full, or the context error if ctx is done
+// while waiting.
+func (l *limiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if atomic.AddInt32(&l.waiting, 1) > l.queue {
+		atomic.AddInt32(&l.waiting, -1)
+		return ErrServerBusy
+	}
+	defer atomic.AddInt32(&l.waiting, -1)
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *limiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// withLimits wraps next so that it runs within the concurrency limits of the
+// method, the service and the server, acquired in that order.
+func (s *Server) withLimits(serviceSpec *service, methodSpec *serviceMethod, next Handler) Handler {
+	return func(ctx context.Context, call *CallInfo) (int, error, interface{}) {
+		limiters := [...]*limiter{methodSpec.limiter, serviceSpec.limiter, s.limiter}
+		for i, l := range limiters {
+			if err := l.acquire(ctx); err != nil {
+				for _, acquired := range limiters[:i] {
+					acquired.release()
+				}
+				if err == ErrServerBusy {
+					atomic.AddInt64(&methodSpec.rejected, 1)
+					return E_BUSY, err, nil
+				}
+				return E_SERVER, err, nil
+			}
+		}
+		defer func() {
+			for _, l := range limiters {
+				l.release()
+			}
+		}()
+		atomic.AddInt64(&methodSpec.inFlight, 1)
+		defer atomic.AddInt64(&methodSpec.inFlight, -1)
+		return next(ctx, call)
+	}
+}
+
+// MethodStats holds the call counters of a method.
+type MethodStats struct {
+	// The method name, as in "Service.Method" with the default name mapper.
+	Method string
+	// The number of calls received.
+	Calls int64
+	// The number of calls currently running.
+	InFlight int64
+	// The number of calls rejected because the server was busy.
+	Rejected int64
+}
+
+// Stats returns the call counters of every registered method, sorted by
+// method name.
+func (s *Server) Stats() []MethodStats {
+	stats := s.services.stats()
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
@@ -0,0 +1,99 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Logger is a leveled, structured logger. The keyvals are alternating keys
+// and values, e.g. "method", "Service.Method", "remote", "1.2.3.4:5678".
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// NopLogger discards everything. It is the default logger of the server, the
+// codecs and the clients.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct {
+}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// NewStdLogger returns a Logger writing to l, or to the standard logger if l
+// is nil, one line per entry in the form:
+//
+//	level=error msg=errRead method=Service.Method remote=1.2.3.4:5678
+func NewStdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &stdLogger{l}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s *stdLogger) Debug(msg string, keyvals ...interface{}) { s.log("debug", msg, keyvals) }
+func (s *stdLogger) Info(msg string, keyvals ...interface{})  { s.log("info", msg, keyvals) }
+func (s *stdLogger) Warn(msg string, keyvals ...interface{})  { s.log("warn", msg, keyvals) }
+func (s *stdLogger) Error(msg string, keyvals ...interface{}) { s.log("error", msg, keyvals) }
+
+func (s *stdLogger) log(level, msg string, keyvals []interface{}) {
+	var b strings.Builder
+	b.WriteString("level=")
+	b.WriteString(level)
+	b.WriteString(" msg=")
+	b.WriteString(formatLogValue(msg))
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(keyvals[i]))
+		b.WriteByte('=')
+		if i+1 < len(keyvals) {
+			b.WriteString(formatLogValue(keyvals[i+1]))
+		} else {
+			b.WriteString("MISSING")
+		}
+	}
+	s.l.Output(3, b.String())
+}
+
+// formatLogValue formats v, quoting it if needed. Byte slices such as raw
+// JSON ids are written as strings.
+func formatLogValue(v interface{}) string {
+	var str string
+	switch value := v.(type) {
+	case string:
+		str = value
+	case error:
+		str = value.Error()
+	case fmt.Stringer:
+		str = value.String()
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			str = string(rv.Bytes())
+		} else {
+			str = fmt.Sprint(v)
+		}
+	}
+	if str == "" || strings.ContainsAny(str, " \t\r\n\"=") {
+		return strconv.Quote(str)
+	}
+	return str
+}
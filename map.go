@@ -6,20 +6,25 @@
 package rpcHttp
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unicode"
 	"unicode/utf8"
 )
 
 var (
-	// Precompute the reflect.Type of error and http.Request
+	// Precompute the reflect.Type of error, http.Request and context.Context
 	typeOfError          = reflect.TypeOf((*error)(nil)).Elem()
 	typeOfRequest        = reflect.TypeOf((*http.Request)(nil)).Elem()
 	typeOfResponseWriter = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	typeOfContext        = reflect.TypeOf((*context.Context)(nil)).Elem()
 )
 
 // ----------------------------------------------------------------------------
@@ -27,19 +32,134 @@ var (
 // ----------------------------------------------------------------------------
 
 type service struct {
-	name     string                    // name of service
-	rcvr     reflect.Value             // receiver of methods for the service
-	rcvrType reflect.Type              // type of the receiver
-	methods  map[string]*serviceMethod // registered methods
+	name       string                    // name of service
+	rcvr       reflect.Value             // receiver of methods, invalid for funcs
+	rcvrType   reflect.Type              // type of the receiver, nil for funcs
+	methods    map[string]*serviceMethod // registered methods
+	positional map[string]*serviceMethod // methods taking parameters by position, not registered yet
+	timeout    time.Duration             // default timeout of the methods
+	limiter    *limiter                  // concurrency limit of the service
+	key        func(string) string       // map key of a method name
+	mapper     NameMapper                // name mapper of the server
+	validators []MethodValidator         // check the methods at registration
 }
 
 type serviceMethod struct {
-	method     reflect.Method // receiver method
-	hasHttpReq bool
-	hasHttpRes bool
-	argsType   reflect.Type // type of the request argument
-	replyType  reflect.Type // type of the response argument
-	counter    int          // used to record the number of calls
+	name                string        // name of the method
+	goName              string        // Go name of the method, used by the options
+	hidden              bool          // hidden from the method enumeration
+	deprecated          string        // deprecation message, if deprecated
+	rejectNotifications bool          // notifications are not allowed
+	params              bool          // args is a struct of the parameters, passed by position
+	fn                  reflect.Value // method value bound to the receiver, or func
+	hasContext          bool
+	hasHttpReq          bool
+	hasHttpRes          bool
+	argsType            reflect.Type  // type of the request argument
+	replyType           reflect.Type  // type of the response argument
+	counter             int64         // used to record the number of calls
+	inFlight            int64         // number of calls currently running
+	rejected            int64         // number of calls rejected by the limiter
+	timeout             time.Duration // timeout of the method, overrides the service one
+	limiter             *limiter      // concurrency limit of the method
+}
+
+// newServiceMethod returns the method calling fn, or nil if the signature of
+// fn is not suitable.
+func newServiceMethod(name string, fn reflect.Value) *serviceMethod {
+	mtype := fn.Type()
+	// Method needs two ins: *args, *reply.
+	// or Method needs three ins: context.Context, *args, *reply.
+	// or Method needs three ins: *http.Request, *args, *reply.
+	// or Method needs four ins: *http.Request, http.ResponseWriter, *args, *reply.
+	// *args may be replaced by one or more parameters passed by position.
+	var hasContext bool
+	var hasHttpReq bool
+	var hasHttpRes bool
+	argIndex := 0
+
+	if mtype.NumIn() <= argIndex || mtype.IsVariadic() {
+		return nil
+	}
+	if firstType := mtype.In(argIndex); firstType == typeOfContext {
+		hasContext = true
+		argIndex++
+	} else if firstType.Kind() == reflect.Ptr && firstType.Elem() == typeOfRequest {
+		hasHttpReq = true
+		argIndex++
+		if mtype.NumIn() > argIndex && mtype.In(argIndex) == typeOfResponseWriter {
+			hasHttpRes = true
+			argIndex++
+		}
+	}
+	if mtype.NumIn() < argIndex+2 {
+		return nil
+	}
+
+	// Last argument must be a pointer and must be exported.
+	reply := mtype.In(mtype.NumIn() - 1)
+	if reply.Kind() != reflect.Ptr || !isExportedOrBuiltin(reply) {
+		return nil
+	}
+	// Arguments in between must be exported. A single pointer is the args,
+	// anything else is a list of parameters gathered in an args struct.
+	var argsType reflect.Type
+	params := mtype.NumIn() - 1 - argIndex
+	if args := mtype.In(argIndex); params == 1 && args.Kind() == reflect.Ptr {
+		if !isExportedOrBuiltin(args) {
+			return nil
+		}
+		argsType = args.Elem()
+		params = 0
+	} else {
+		types := make([]reflect.Type, params)
+		for i := range types {
+			types[i] = mtype.In(argIndex + i)
+			if !isExportedOrBuiltin(types[i]) {
+				return nil
+			}
+		}
+		argsType = paramsType(types, nil)
+	}
+
+	// Method needs
+	// one out: 		error(message).
+	// or two out: 		errorNumber(int) error(message).
+	// or three out: 	errorNumber(int) error(message) data(interface).
+	if mtype.NumOut() == 1 {
+		if returnType := mtype.Out(0); returnType != typeOfError {
+			return nil
+		}
+	} else if mtype.NumOut() == 2 {
+		if returnType := mtype.Out(0); returnType.Kind() != reflect.Int {
+			return nil
+		}
+		if returnType := mtype.Out(1); returnType != typeOfError {
+			return nil
+		}
+	} else if mtype.NumOut() == 3 {
+		if returnType := mtype.Out(0); returnType.Kind() != reflect.Int {
+			return nil
+		}
+		if returnType := mtype.Out(1); returnType != typeOfError {
+			return nil
+		}
+		// no.2 unlimited format
+	} else {
+		return nil
+	}
+
+	return &serviceMethod{
+		name:       name,
+		goName:     name,
+		fn:         fn,
+		argsType:   argsType,
+		replyType:  reply.Elem(),
+		params:     params > 0,
+		hasContext: hasContext,
+		hasHttpRes: hasHttpRes,
+		hasHttpReq: hasHttpReq,
+	}
 }
 
 // ----------------------------------------------------------------------------
@@ -48,208 +168,295 @@ type serviceMethod struct {
 
 // serviceMap is a registry for services.
 type serviceMap struct {
-	mutex            sync.Mutex
+	mutex            sync.RWMutex
 	services         map[string]*service
 	methodIgnoreCase bool
+	mapper           NameMapper
+	validator        MethodValidator // methods supported by the codecs
 }
 
 // register adds a new service using reflection to extract its methods.
-func (m *serviceMap) register(rcvr interface{}, name string) error {
+func (m *serviceMap) register(rcvr interface{}, name string, opts ...ServiceOption) error {
+	s, err := m.newService(rcvr, name, opts...)
+	if err != nil {
+		return err
+	}
+	// Add to the map.
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.services == nil {
+		m.services = make(map[string]*service)
+	} else if _, ok := m.services[m.key(s.name)]; ok {
+		return fmt.Errorf("rpc: service already defined: %q", s.name)
+	}
+	m.services[m.key(s.name)] = s
+	return nil
+}
+
+// registerFunc adds a function to the service named by the first part of
+// name, creating the service if needed.
+func (m *serviceMap) registerFunc(name string, fn interface{}) error {
+	names := m.nameMapper().Split(name)
+	if len(names) == 0 || names[0].Service == "" {
+		return fmt.Errorf("rpc: func name ill-formed: %q", name)
+	}
+	parts := []string{names[0].Service, names[0].Method}
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return fmt.Errorf("rpc: %q is not a func", name)
+	}
+	spec := newServiceMethod(parts[1], fnValue)
+	if spec == nil {
+		return fmt.Errorf("rpc: func %q has no suitable signature", name)
+	}
+	if m.validator != nil {
+		if err := validateMethod(m.validator, name, spec); err != nil {
+			return err
+		}
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.services == nil {
+		m.services = make(map[string]*service)
+	}
+	// Services are never modified once added, so that calls in flight are
+	// not affected: the service is copied with the new method.
+	s := &service{
+		name:    parts[0],
+		methods: make(map[string]*serviceMethod),
+	}
+	if old, ok := m.services[m.key(s.name)]; ok {
+		if old.rcvr.IsValid() {
+			return fmt.Errorf("rpc: service already defined: %q", s.name)
+		}
+		if _, ok := old.methods[m.key(spec.name)]; ok {
+			return fmt.Errorf("rpc: method already defined: %q", name)
+		}
+		*s = *old
+		s.methods = make(map[string]*serviceMethod, len(old.methods)+1)
+		for k, v := range old.methods {
+			s.methods[k] = v
+		}
+	}
+	s.methods[m.key(spec.name)] = spec
+	m.services[m.key(s.name)] = s
+	return nil
+}
+
+// replace swaps a registered service for a new one built from rcvr. Calls in
+// flight keep running on the old receiver.
+func (m *serviceMap) replace(rcvr interface{}, name string, opts ...ServiceOption) error {
+	s, err := m.newService(rcvr, name, opts...)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.services[m.key(s.name)]; !ok {
+		return fmt.Errorf("rpc: service not defined: %q", s.name)
+	}
+	m.services[m.key(s.name)] = s
+	return nil
+}
+
+// unregister removes a registered service. Calls in flight keep running.
+func (m *serviceMap) unregister(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.services[m.key(name)]; !ok {
+		return fmt.Errorf("rpc: service not defined: %q", name)
+	}
+	delete(m.services, m.key(name))
+	return nil
+}
+
+// key returns the map key of a service or method name.
+func (m *serviceMap) key(name string) string {
+	if m.methodIgnoreCase {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// newService returns a service using reflection to extract its methods.
+func (m *serviceMap) newService(rcvr interface{}, name string, opts ...ServiceOption) (*service, error) {
 	// Setup service.
 	s := &service{
-		name:     name,
-		rcvr:     reflect.ValueOf(rcvr),
-		rcvrType: reflect.TypeOf(rcvr),
-		methods:  make(map[string]*serviceMethod),
+		name:       name,
+		rcvr:       reflect.ValueOf(rcvr),
+		rcvrType:   reflect.TypeOf(rcvr),
+		methods:    make(map[string]*serviceMethod),
+		positional: make(map[string]*serviceMethod),
 	}
 	if name == "" {
 		s.name = reflect.Indirect(s.rcvr).Type().Name()
 		if !isExported(s.name) {
-			return fmt.Errorf("rpc: type %q is not exported", s.name)
+			return nil, fmt.Errorf("rpc: type %q is not exported", s.name)
 		}
+		s.name = m.nameMapper().Name(s.name)
 	}
 	if s.name == "" {
-		return fmt.Errorf("rpc: no service name for type %q",
+		return nil, fmt.Errorf("rpc: no service name for type %q",
 			s.rcvrType.String())
 	}
 	// Setup methods.
 	for i := 0; i < s.rcvrType.NumMethod(); i++ {
 		method := s.rcvrType.Method(i)
-		mtype := method.Type
 		// Method must be exported.
 		if method.PkgPath != "" {
 			continue
 		}
-		// Method needs three ins: receiver, *args, *reply.
-		// or Method needs four ins: receiver, *http.Request, *args, *reply.
-		// or Method needs five ins: receiver, *http.Request, *http.Response, *args, *reply.
-
-		var hasHttpReq bool
-		var hasHttpRes bool
-		if mtype.NumIn() == 3 {
-			hasHttpReq = false
-			hasHttpRes = false
-		} else if mtype.NumIn() == 4 {
-			hasHttpReq = true
-			hasHttpRes = false
-		} else if mtype.NumIn() == 5 {
-			hasHttpReq = true
-			hasHttpRes = true
-		} else {
-			continue
-		}
-
-		argIndex := 1
-
-		if hasHttpReq {
-			// First argument must be a pointer and must be http.Request.
-			reqType := mtype.In(argIndex)
-			if reqType.Kind() != reflect.Ptr || reqType.Elem() != typeOfRequest {
-				continue
-			}
-			argIndex++
-		}
-
-		if hasHttpRes {
-			// First argument must be a pointer and must be http.Request.
-			reqType := mtype.In(argIndex)
-			//if reqType.Kind() != reflect.Ptr || reqType.Elem() != typeOfResponseWriter {
-			if reqType != typeOfResponseWriter {
-				continue
-			}
-			argIndex++
-		}
-		// Second argument must be a pointer and must be exported.
-		args := mtype.In(argIndex)
-		if args.Kind() != reflect.Ptr || !isExportedOrBuiltin(args) {
-			continue
-		}
-		argIndex++
-		// Third argument must be a pointer and must be exported.
-		reply := mtype.In(argIndex)
-		if reply.Kind() != reflect.Ptr || !isExportedOrBuiltin(reply) {
-			continue
-		}
-		argIndex++
-
-		// Method needs
-		// one out: 		error(message).
-		// or two out: 		errorNumber(int) error(message).
-		// or three out: 	errorNumber(int) error(message) data(interface).
-		if mtype.NumOut() == 1 {
-			if returnType := mtype.Out(0); returnType != typeOfError {
-				continue
-			}
-		} else if mtype.NumOut() == 2 {
-			if returnType := mtype.Out(0); returnType.Kind() != reflect.Int {
-				continue
+		// The method value is bound to the receiver, so its type has no
+		// receiver argument.
+		fn := s.rcvr.Method(i)
+		if spec := newServiceMethod(method.Name, fn); spec != nil {
+			spec.name = m.nameMapper().Name(method.Name)
+			if spec.params {
+				// Registered by the WithPositionalParams and
+				// WithMethodParams options only.
+				s.positional[m.key(spec.name)] = spec
+			} else {
+				s.methods[m.key(spec.name)] = spec
 			}
-			if returnType := mtype.Out(1); returnType != typeOfError {
-				continue
-			}
-		} else if mtype.NumOut() == 3 {
-			if returnType := mtype.Out(0); returnType.Kind() != reflect.Int {
-				continue
-			}
-			if returnType := mtype.Out(1); returnType != typeOfError {
-				continue
-			}
-			// no.2 unlimited format
-		} else {
-			continue
 		}
-
-		if m.methodIgnoreCase {
-			s.methods[strings.ToLower(method.Name)] = &serviceMethod{
-				method:     method,
-				argsType:   args.Elem(),
-				replyType:  reply.Elem(),
-				hasHttpRes: hasHttpRes,
-				hasHttpReq: hasHttpReq,
-			}
-		} else {
-			s.methods[method.Name] = &serviceMethod{
-				method:     method,
-				argsType:   args.Elem(),
-				replyType:  reply.Elem(),
-				hasHttpRes: hasHttpRes,
-				hasHttpReq: hasHttpReq,
-			}
+	}
+	s.key = m.key
+	s.mapper = m.nameMapper()
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
 		}
-
 	}
 	if len(s.methods) == 0 {
-		return fmt.Errorf("rpc: %q has no exported methods of suitable type",
+		return nil, fmt.Errorf("rpc: %q has no exported methods of suitable type",
 			s.name)
 	}
-	// Add to the map.
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-	if m.services == nil {
-		m.services = make(map[string]*service)
-	} else if _, ok := m.services[s.name]; ok {
-		return fmt.Errorf("rpc: service already defined: %q", s.name)
+	validators := s.validators
+	if m.validator != nil {
+		validators = append(validators[:len(validators):len(validators)], m.validator)
 	}
-	if m.methodIgnoreCase {
-		m.services[strings.ToLower(s.name)] = s
-	} else {
-		m.services[s.name] = s
+	if err := s.validate(validators); err != nil {
+		return nil, err
 	}
-	return nil
+	return s, nil
 }
 
 // get returns a registered service given a method name.
 //
-// The method name uses a dotted notation as in "Service.Method".
+// The method name is parsed by the name mapper, by default in the dotted
+// notation as in "Service.Method". An unqualified method name is looked up in
+// every service and must match a single one.
 func (m *serviceMap) get(method string) (*service, *serviceMethod, error) {
-	methodForDisplay := method
-	if m.methodIgnoreCase {
-		method = strings.ToLower(method)
-	}
-	parts := strings.Split(method, ".")
-	if len(parts) != 2 {
-		// for method name not include period char(.)
-		if len(parts) == 1 {
-			for _, service := range m.services {
-				serviceMethod := service.methods[parts[0]]
-				if serviceMethod == nil {
-					continue
-				}
-				return service, serviceMethod, nil
-			}
-		}
-
+	names := m.nameMapper().Split(method)
+	if len(names) == 0 {
 		err := fmt.Errorf("rpc: service/method request ill-formed: %q", method)
 		return nil, nil, err
 	}
-	m.mutex.Lock()
-	service := m.services[parts[0]]
-	m.mutex.Unlock()
-	if service == nil {
-		err := fmt.Errorf("rpc: can't find service %q", methodForDisplay)
-		return nil, nil, err
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	var matches []*service
+	var unqualified string
+	serviceFound := false
+	for _, name := range names {
+		if name.Service == "" {
+			unqualified = name.Method
+			continue
+		}
+		if service := m.services[m.key(name.Service)]; service != nil {
+			serviceFound = true
+			if service.methods[m.key(name.Method)] != nil {
+				matches = append(matches, service)
+			}
+		}
 	}
-	serviceMethod := service.methods[parts[1]]
-	if serviceMethod == nil {
-		err := fmt.Errorf("rpc: can't find method %q", methodForDisplay)
+	if unqualified != "" && len(matches) == 0 {
+		for _, service := range m.services {
+			if service.methods[m.key(unqualified)] != nil {
+				matches = append(matches, service)
+			}
+		}
+		// Sort so that the error below does not depend on the map order.
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].name < matches[j].name
+		})
+	}
+	switch {
+	case len(matches) == 1:
+		service := matches[0]
+		for _, name := range names {
+			if name.Service == "" || m.key(name.Service) == m.key(service.name) {
+				return service, service.methods[m.key(name.Method)], nil
+			}
+		}
+	case len(matches) > 1:
+		candidates := make([]string, len(matches))
+		for i, service := range matches {
+			candidates[i] = service.name
+		}
+		err := fmt.Errorf("rpc: method %q is ambiguous, found in services %s",
+			method, strings.Join(candidates, ", "))
+		return nil, nil, err
+	case unqualified == "" && !serviceFound:
+		err := fmt.Errorf("rpc: can't find service %q", method)
 		return nil, nil, err
 	}
-	return service, serviceMethod, nil
+	err := fmt.Errorf("rpc: can't find method %q", method)
+	return nil, nil, err
+}
+
+// nameMapper returns the name mapper of the services.
+func (m *serviceMap) nameMapper() NameMapper {
+	if m.mapper == nil {
+		return DefaultNameMapper
+	}
+	return m.mapper
 }
 
 func (m *serviceMap) enumMethodInfo() (methodNames []string) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	for _, s := range m.services {
 		for mn, mv := range s.methods {
-			methodNames = append(methodNames, fmt.Sprintf(`%v.%v(calls:%v)`, s.name, mn, mv.counter))
+			if mv.hidden || mn != m.key(mv.name) {
+				// Hidden method or alias.
+				continue
+			}
+			methodNames = append(methodNames, fmt.Sprintf(`%v(calls:%v)`, m.nameMapper().Join(s.name, mv.name), atomic.LoadInt64(&mv.counter)))
 		}
 	}
 	return methodNames
 }
 
+func (m *serviceMap) stats() (stats []MethodStats) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, s := range m.services {
+		for mn, mv := range s.methods {
+			if mn != m.key(mv.name) {
+				// Alias.
+				continue
+			}
+			stats = append(stats, MethodStats{
+				Method:   m.nameMapper().Join(s.name, mv.name),
+				Calls:    atomic.LoadInt64(&mv.counter),
+				InFlight: atomic.LoadInt64(&mv.inFlight),
+				Rejected: atomic.LoadInt64(&mv.rejected),
+			})
+		}
+	}
+	return stats
+}
+
 func (m *serviceMap) enumMethod() (methodNames []string) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	for _, s := range m.services {
-		for _, mv := range s.methods {
-			methodNames = append(methodNames, fmt.Sprintf(`%s.%s`, s.name, mv.method.Name))
+		for mn, mv := range s.methods {
+			if mv.hidden || mn != m.key(mv.name) {
+				// Hidden method or alias.
+				continue
+			}
+			methodNames = append(methodNames, m.nameMapper().Join(s.name, mv.name))
 		}
 	}
 	return methodNames
@@ -261,6 +468,25 @@ func isExported(name string) bool {
 	return unicode.IsUpper(rune)
 }
 
+// paramsType returns the args struct of a method taking the parameters of the
+// given types. Each parameter is a field named after names, or "p0", "p1"...
+// if names is nil, for the codecs decoding params by name.
+func paramsType(types []reflect.Type, names []string) reflect.Type {
+	fields := make([]reflect.StructField, len(types))
+	for i, t := range types {
+		name := fmt.Sprintf("p%d", i)
+		if names != nil {
+			name = names[i]
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("P%d", i),
+			Type: t,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:%[1]q msgpack:%[1]q bson:%[1]q xmlrpc:%[1]q cbor:%[1]q`, name)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
 // isExportedOrBuiltin returns true if a type is exported or a builtin.
 func isExportedOrBuiltin(t reflect.Type) bool {
 	for t.Kind() == reflect.Ptr {
@@ -2,10 +2,13 @@ package msgpackrpc
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"math/rand"
 	"net/http"
 
+	"github.com/Limard/rpcHttp"
+
 	"github.com/vmihailenco/msgpack"
 )
 
@@ -23,11 +26,61 @@ type clientRequest struct {
 type clientResponse struct {
 	Id      interface{} `msgpack:"id"`
 	Version string      `msgpack:"msgpackrpc"`
-	Result  interface{} `msgpack:"result"`
-	Error   interface{} `msgpack:"error"`
+	Result  RawMessage  `msgpack:"result"`
+	Error   RawMessage  `msgpack:"error"`
+}
+
+// Client calls methods on MessagePack-RPC servers. It is safe for concurrent
+// use. Call and CallContext use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	logger     rpcHttp.Logger
+	framing    Framing
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func encodeClientRequest(method string, args interface{}) ([]byte, error) {
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithClientFraming sets the framing of the messages of the client,
+// FramingEnvelope by default. FramingAuto is the same as FramingEnvelope.
+func WithClientFraming(framing Framing) ClientOption {
+	return func(c *Client) {
+		c.framing = framing
+	}
+}
+
+// WithClientLogger sets the logger of the client.
+func WithClientLogger(logger rpcHttp.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+func encodeClientRequest(framing Framing, method string, args interface{}) ([]byte, error) {
+	if framing == FramingArray {
+		return encodeArrayRequest(rand.Uint32(), method, args)
+	}
 	c := &clientRequest{
 		Version: "1.0",
 		Method:  method,
@@ -37,36 +90,48 @@ func encodeClientRequest(method string, args interface{}) ([]byte, error) {
 	return msgpack.Marshal(c)
 }
 
-func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
+func decodeClientResponse(framing Framing, r io.Reader, reply interface{}) (e error) {
 	var c clientResponse
-	if err := msgpack.NewDecoder(r).Decode(&c); err != nil {
+	var err error
+	if framing == FramingArray {
+		err = decodeArrayResponse(msgpack.NewDecoder(r), &c)
+	} else {
+		err = msgpack.NewDecoder(r).Decode(&c)
+	}
+	if err != nil {
 		return &Error{
 			Code:    E_PARSE,
 			Message: err.Error()}
 	}
 
 	// Error
-	if c.Error != nil {
+	if len(c.Error) != 0 {
+		var message string
+		if msgpack.Unmarshal(c.Error, &message) == nil {
+			// Other MessagePack-RPC servers may send a plain string.
+			return &Error{
+				Code:    E_SERVER,
+				Message: message,
+			}
+		}
 		replyError := &Error{}
-		tempBuf, _ := msgpack.Marshal(c.Error)
-		if err := msgpack.Unmarshal(tempBuf, replyError); err != nil {
+		if err := msgpack.Unmarshal(c.Error, replyError); err != nil {
 			return &Error{
 				Code:    E_PARSE,
-				Message: string(tempBuf),
+				Message: string(c.Error),
 			}
 		}
 		return replyError
 	}
 
 	// Result
-	if c.Result == nil {
+	if len(c.Result) == 0 {
 		return &Error{
 			Code:    E_BAD_PARAMS,
 			Message: "result is null",
 		}
 	}
-	tempBuf, _ := msgpack.Marshal(c.Result)
-	if err := msgpack.Unmarshal(tempBuf, reply); err != nil {
+	if err := msgpack.Unmarshal(c.Result, reply); err != nil {
 		return &Error{
 			Code:    E_PARSE,
 			Message: err.Error(),
@@ -77,23 +142,55 @@ func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
 }
 
 func Call(url string, method string, request interface{}, reply interface{}) (e error) {
-	jsonReqBuf, err := encodeClientRequest(method, request)
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+// CallContext calls method with client. The deadline of ctx, if any, is sent
+// to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (c *Client) Call(url string, method string, request interface{}, reply interface{}) error {
+	return c.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (c *Client) CallContext(ctx context.Context, url string, method string, request interface{}, reply interface{}) error {
+	fields := []interface{}{"method", method, "codec", ContentType, "remote", url}
+	reqBuf, err := encodeClientRequest(c.framing, method, request)
 	if err != nil {
+		c.logger.Error("encodeClientRequest", append(fields, "err", err)...)
 		return &Error{
 			Code:    E_INVALID_REQ,
 			Message: err.Error()}
 	}
 
-	jsonReqBufR := bytes.NewReader(jsonReqBuf)
-	rsp, err := http.Post(url, ContentType, jsonReqBufR)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBuf))
 	if err != nil {
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("http.Post", append(fields, "err", err)...)
 		return &Error{
 			Code:    E_SERVER,
 			Message: err.Error()}
 	}
 	defer rsp.Body.Close()
 
-	return decodeClientResponse(rsp.Body, reply)
+	err = decodeClientResponse(c.framing, rsp.Body, reply)
+	if replyErr, ok := err.(*Error); ok && replyErr.Code == E_PARSE {
+		c.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+	}
+	return err
 }
 
 func ConvertError(err error) (replyError *Error) {
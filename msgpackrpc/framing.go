@@ -0,0 +1,135 @@
+package msgpackrpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack"
+	"github.com/vmihailenco/msgpack/codes"
+)
+
+// Framing is the layout of the messages of the codec.
+type Framing int
+
+const (
+	// FramingEnvelope is the map envelope with a "msgpackrpc" version key,
+	// as {"msgpackrpc": "1.0", "method": ..., "params": ..., "id": ...}.
+	FramingEnvelope Framing = iota
+	// FramingArray is the array framing of the MessagePack-RPC
+	// specification: [0, msgid, method, params] for requests,
+	// [1, msgid, error, result] for responses and [2, method, params] for
+	// notifications.
+	FramingArray
+	// FramingAuto answers each request in the framing it was sent in.
+	FramingAuto
+)
+
+// MessagePack-RPC message types.
+const (
+	msgRequest      = 0
+	msgResponse     = 1
+	msgNotification = 2
+)
+
+var errInvalidMessage = errors.New("msgpackrpc: invalid message")
+
+// isArray reports whether c starts a msgpack array.
+func isArray(c codes.Code) bool {
+	return codes.IsFixedArray(c) || c == codes.Array16 || c == codes.Array32
+}
+
+// isMap reports whether c starts a msgpack map.
+func isMap(c codes.Code) bool {
+	return codes.IsFixedMap(c) || c == codes.Map16 || c == codes.Map32
+}
+
+// decodeArrayRequest decodes a request or a notification in the array
+// framing into req. It reports whether the message is a notification.
+func decodeArrayRequest(d *msgpack.Decoder, req *serverRequest) (notification bool, err error) {
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return false, err
+	}
+	msgType, err := d.DecodeInt()
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case msgType == msgRequest && n == 4:
+		msgid, err := d.DecodeUint32()
+		if err != nil {
+			return false, err
+		}
+		req.Id = msgid
+	case msgType == msgNotification && n == 3:
+		notification = true
+	default:
+		return false, errInvalidMessage
+	}
+	if req.Method, err = d.DecodeString(); err != nil {
+		return notification, err
+	}
+	return notification, d.Decode(&req.Params)
+}
+
+// encodeArrayResponse encodes the response in the array framing.
+func encodeArrayResponse(res *serverResponse) ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := msgpack.NewEncoder(&buffer).UseCompactEncoding(true)
+	if err := enc.Encode([]interface{}{msgResponse, res.Id, res.Error, res.Result}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// readArrayParams decodes the params array of a request in the array framing
+// into args. The values fill the fields of the args struct by position, or
+// args itself if it is not a struct. An array holding a single map is read as
+// the whole args, as sent by Call.
+func readArrayParams(params RawMessage, args interface{}) error {
+	d := msgpack.NewDecoder(bytes.NewReader(params))
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return fmt.Errorf("params must be an array: %v", err)
+	}
+	if n == 1 {
+		code, err := d.PeekCode()
+		if err != nil {
+			return err
+		}
+		if isMap(code) || reflect.ValueOf(args).Elem().Kind() != reflect.Struct {
+			return d.Decode(args)
+		}
+	}
+	// msgpack decodes an array into the fields of a struct in order.
+	return msgpack.Unmarshal(params, args)
+}
+
+// encodeArrayRequest encodes a request in the array framing, sending args as
+// the single param.
+func encodeArrayRequest(msgid uint32, method string, args interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := msgpack.NewEncoder(&buffer).UseCompactEncoding(true)
+	if err := enc.Encode([]interface{}{msgRequest, msgid, method, []interface{}{args}}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeArrayResponse decodes a response in the array framing into c.
+func decodeArrayResponse(d *msgpack.Decoder, c *clientResponse) error {
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	msgType, err := d.DecodeInt()
+	if err != nil {
+		return err
+	}
+	if msgType != msgResponse || n != 4 {
+		return errInvalidMessage
+	}
+	return d.DecodeMulti(&c.Id, &c.Error, &c.Result)
+}
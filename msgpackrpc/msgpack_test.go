@@ -0,0 +1,211 @@
+package msgpackrpc
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Limard/rpcHttp"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+var ErrResponseError = errors.New("response error")
+
+type Service1Request struct {
+	A int
+	B int
+}
+
+type Service1Response struct {
+	Result int
+}
+
+type Service1 struct {
+}
+
+func (t *Service1) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A * req.B
+	return nil
+}
+
+func (t *Service1) ResponseError(r *http.Request, req *Service1Request, res *Service1Response) error {
+	return ErrResponseError
+}
+
+func (t *Service1) Greet(name string, times int, res *string) error {
+	*res = strings.Repeat("hello "+name+" ", times)
+	return nil
+}
+
+func newServer(opts ...CodecOption) *rpcHttp.Server {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(opts...), ContentType)
+	s.RegisterServiceWithOptions(new(Service1), "", rpcHttp.WithPositionalParams())
+	return s
+}
+
+func serveRaw(s *rpcHttp.Server, body []byte) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", ContentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+// fixture concatenates msgpack bytes and strings.
+func fixture(parts ...interface{}) []byte {
+	var b []byte
+	for _, part := range parts {
+		switch part := part.(type) {
+		case int:
+			b = append(b, byte(part))
+		case string:
+			b = append(b, part...)
+		}
+	}
+	return b
+}
+
+func TestArrayFraming(t *testing.T) {
+	t.Parallel()
+	s := newServer(WithFraming(FramingArray))
+
+	// [0, 1, "Service1.Multiply", [4, 2]]
+	w := serveRaw(s, fixture(0x94, 0x00, 0x01, 0xb1, "Service1.Multiply", 0x92, 0x04, 0x02))
+	// [1, 1, nil, {"Result": 8}]
+	if want := fixture(0x94, 0x01, 0x01, 0xc0, 0x81, 0xa6, "Result", 0x08); !bytes.Equal(w.Body.Bytes(), want) {
+		t.Errorf("Wrong response: got % x, want % x", w.Body.Bytes(), want)
+	}
+
+	// [0, 2, "Service1.ResponseError", [4, 2]]
+	w = serveRaw(s, fixture(0x94, 0x00, 0x02, 0xb6, "Service1.ResponseError", 0x92, 0x04, 0x02))
+	// [1, 2, {"code": -32000, "message": "response error", "data": nil}, nil]
+	want := fixture(0x94, 0x01, 0x02, 0x83, 0xa4, "code", 0xd1, 0x83, 0x00,
+		0xa7, "message", 0xae, "response error", 0xa4, "data", 0xc0, 0xc0)
+	if !bytes.Equal(w.Body.Bytes(), want) {
+		t.Errorf("Wrong error response: got % x, want % x", w.Body.Bytes(), want)
+	}
+
+	// [0, 3, "Service1.Greet", ["go", 2]]
+	w = serveRaw(s, fixture(0x94, 0x00, 0x03, 0xae, "Service1.Greet", 0x92, 0xa2, "go", 0x02))
+	// [1, 3, nil, "hello go hello go "]
+	if want := fixture(0x94, 0x01, 0x03, 0xc0, 0xb2, "hello go hello go "); !bytes.Equal(w.Body.Bytes(), want) {
+		t.Errorf("Wrong positional response: got % x, want % x", w.Body.Bytes(), want)
+	}
+
+	// [2, "Service1.Multiply", [4, 2]]
+	w = serveRaw(s, fixture(0x93, 0x02, 0xb1, "Service1.Multiply", 0x92, 0x04, 0x02))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected no response to a notification, got %d % x", w.Code, w.Body.Bytes())
+	}
+
+	// [5, 4, "Service1.Multiply", [4, 2]]
+	w = serveRaw(s, fixture(0x94, 0x05, 0x04, 0xb1, "Service1.Multiply", 0x92, 0x04, 0x02))
+	var res clientResponse
+	if err := decodeClientResponse(FramingArray, w.Body, &res); err == nil || err.(*Error).Code != E_INVALID_REQ {
+		t.Errorf("Expected an invalid request error, got %v", err)
+	}
+
+	// [1, 5, "oops", nil] from another server.
+	err := decodeClientResponse(FramingArray, bytes.NewReader(fixture(0x94, 0x01, 0x05, 0xa4, "oops", 0xc0)), &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Message != "oops" {
+		t.Errorf("Wrong string error: %v", err)
+	}
+}
+
+func TestFramingClient(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newServer(WithFraming(FramingAuto)))
+	defer server.Close()
+
+	for _, framing := range []Framing{FramingEnvelope, FramingArray} {
+		client := NewClient(WithClientFraming(framing))
+		var res Service1Response
+		if err := client.Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+			t.Errorf("Framing %d: expected 8, got %d, %v", framing, res.Result, err)
+		}
+		err := client.Call(server.URL, "Service1.ResponseError", &Service1Request{4, 2}, &res)
+		if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_SERVER || replyErr.Message != ErrResponseError.Error() {
+			t.Errorf("Framing %d: expected error %d %q, got %v", framing, E_SERVER, ErrResponseError, err)
+		}
+	}
+}
+
+func TestMethodParams(t *testing.T) {
+	t.Parallel()
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	if err := s.RegisterServiceWithOptions(new(Service1), "", rpcHttp.WithMethodParams("Greet", "name", "times")); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	params := struct {
+		Times int    `msgpack:"times"`
+		Name  string `msgpack:"name"`
+	}{2, "go"}
+	var res string
+	if err := Call(server.URL, "Service1.Greet", &params, &res); err != nil || res != "hello go hello go " {
+		t.Errorf("Wrong by-name response: %q, %v", res, err)
+	}
+}
+
+type benchItem struct {
+	ID   int64
+	Name string
+	Data []byte
+}
+
+type benchArgs struct {
+	Items []benchItem
+}
+
+var benchSizes = []int{1, 100, 10000}
+
+func newBenchArgs(n int) *benchArgs {
+	args := &benchArgs{Items: make([]benchItem, n)}
+	for i := range args.Items {
+		args.Items[i] = benchItem{ID: int64(i) << 40, Name: "item", Data: []byte("0123456789abcdef")}
+	}
+	return args
+}
+
+func BenchmarkReadRequest(b *testing.B) {
+	codec := NewCodec()
+	for _, n := range benchSizes {
+		body, _ := encodeClientRequest(FramingEnvelope, "Bench.Read", newBenchArgs(n))
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				r, _ := http.NewRequest("POST", "/", bytes.NewReader(body))
+				var args benchArgs
+				if err := codec.NewRequest(r).ReadRequest(&args); err != nil || len(args.Items) != n {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeClientResponse(b *testing.B) {
+	for _, n := range benchSizes {
+		body, _ := msgpack.Marshal(&serverResponse{Version: "1.0", Result: newBenchArgs(n), Id: 1})
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				var reply benchArgs
+				if err := decodeClientResponse(FramingEnvelope, bytes.NewReader(body), &reply); err != nil || len(reply.Items) != n {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
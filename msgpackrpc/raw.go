@@ -0,0 +1,19 @@
+package msgpackrpc
+
+// RawMessage is a raw encoded msgpack value. It holds the params and the
+// results of the messages until they are decoded into their target type.
+type RawMessage []byte
+
+// MarshalMsgpack returns m as the msgpack encoding of m, nil if m is empty.
+func (m RawMessage) MarshalMsgpack() ([]byte, error) {
+	if len(m) == 0 {
+		return []byte{0xc0}, nil
+	}
+	return m, nil
+}
+
+// UnmarshalMsgpack sets *m to a copy of data.
+func (m *RawMessage) UnmarshalMsgpack(data []byte) error {
+	*m = append((*m)[0:0], data...)
+	return nil
+}
@@ -1,7 +1,6 @@
 package msgpackrpc
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/Limard/rpcHttp"
@@ -9,19 +8,45 @@ import (
 	"github.com/vmihailenco/msgpack"
 )
 
-func NewCodec() *Codec {
-	return &Codec{encSel: rpcHttp.DefaultEncoderSelector}
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{encSel: rpcHttp.DefaultEncoderSelector, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithLogger sets the logger of the codec.
+func WithLogger(logger rpcHttp.Logger) CodecOption {
+	return func(c *Codec) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithFraming sets the framing of the messages of the codec,
+// FramingEnvelope by default.
+func WithFraming(framing Framing) CodecOption {
+	return func(c *Codec) {
+		c.framing = framing
+	}
 }
 
 // Codec creates a CodecRequest to process each request.
 type Codec struct {
-	encSel rpcHttp.EncoderSelector
+	encSel  rpcHttp.EncoderSelector
+	logger  rpcHttp.Logger
+	framing Framing
 }
 
 type serverRequest struct {
 	Version string      `msgpack:"msgpackrpc"`
 	Method  string      `msgpack:"method"`
-	Params  interface{} `msgpack:"params"`
+	Params  RawMessage  `msgpack:"params"`
 	Id      interface{} `msgpack:"id"`
 }
 
@@ -34,22 +59,52 @@ type serverResponse struct {
 
 func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
 	req := new(serverRequest)
-	err := msgpack.NewDecoder(r.Body).Decode(req)
+	framing := c.framing
+	notification := false
+	d := msgpack.NewDecoder(r.Body)
+	if framing == FramingAuto {
+		framing = FramingEnvelope
+		if code, err := d.PeekCode(); err == nil && isArray(code) {
+			framing = FramingArray
+		}
+	}
+	var err error
+	if framing == FramingArray {
+		notification, err = decodeArrayRequest(d, req)
+	} else {
+		err = d.Decode(req)
+	}
 	if err != nil {
+		code := E_PARSE
+		if err == errInvalidMessage {
+			code = E_INVALID_REQ
+		}
 		err = &Error{
-			Code:    E_PARSE,
+			Code:    code,
 			Message: err.Error(),
 			Data:    req,
 		}
 	}
 	r.Body.Close()
-	return &CodecRequest{request: req, err: err, encoder: c.encSel.Select(r)}
+	return &CodecRequest{
+		request:      req,
+		err:          err,
+		framing:      framing,
+		notification: notification,
+		encoder:      c.encSel.Select(r),
+		logger:       c.logger,
+		remote:       r.RemoteAddr,
+	}
 }
 
 type CodecRequest struct {
-	request *serverRequest
-	err     error
-	encoder rpcHttp.Encoder
+	request      *serverRequest
+	err          error
+	framing      Framing // FramingEnvelope or FramingArray
+	notification bool
+	encoder      rpcHttp.Encoder
+	logger       rpcHttp.Logger
+	remote       string
 }
 
 func (c *CodecRequest) Method() (string, error) {
@@ -59,13 +114,32 @@ func (c *CodecRequest) Method() (string, error) {
 	return "", c.err
 }
 
+// RequestID returns the id of the current request.
+func (c *CodecRequest) RequestID() interface{} {
+	return c.request.Id
+}
+
+// IsNotification reports whether the request is a notification of the array
+// framing, which is answered with an empty response.
+func (c *CodecRequest) IsNotification() bool {
+	return c.notification
+}
+
 func (c *CodecRequest) ReadRequest(args interface{}) error {
-	if c.err == nil && c.request.Params != nil {
-		tempBuf, _ := msgpack.Marshal(c.request.Params)
-		if err := msgpack.Unmarshal(tempBuf, args); err != nil {
+	if c.err == nil && len(c.request.Params) != 0 && c.framing == FramingArray {
+		if err := readArrayParams(c.request.Params, args); err != nil {
+			c.logger.Warn("invalid params", c.logFields("err", err)...)
+			c.err = &Error{
+				Code:    E_BAD_PARAMS,
+				Message: err.Error(),
+				Data:    c.request.Params,
+			}
+		}
+	} else if c.err == nil && len(c.request.Params) != 0 {
+		if err := msgpack.Unmarshal(c.request.Params, args); err != nil {
 			params := [1]interface{}{args}
-			if err = msgpack.Unmarshal(tempBuf, &params); err != nil {
-				log.Printf("ERROR: %s", string(tempBuf))
+			if err = msgpack.Unmarshal(c.request.Params, &params); err != nil {
+				c.logger.Warn("invalid params", c.logFields("err", err)...)
 				c.err = &Error{
 					Code:    E_INVALID_REQ,
 					Message: err.Error(),
@@ -105,21 +179,52 @@ func (c *CodecRequest) WriteErrorResponse(w http.ResponseWriter, code int, err e
 }
 
 func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, res *serverResponse) {
+	if c.framing == FramingArray {
+		c.writeArrayResponse(w, res)
+		return
+	}
 	if c.request.Id != nil {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
 		w.Header().Set("Content-Type", "application/msgpack; charset=utf-8")
 
-		var buffer []byte
-		var err error
-		//buffer, err = msgpack.Marshal(c.request)
-
-		buffer, err = msgpack.Marshal(res)
-		w.Write(buffer)
-
+		buffer, err := msgpack.Marshal(res)
 		if err != nil {
-			log.Println("msgpack Encode:", err.Error())
+			c.logger.Error("msgpack Encode", c.logFields("err", err)...)
 			rpcHttp.WriteError(w, 400, err.Error())
+			return
 		}
+		if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+			c.logger.Warn("write response", c.logFields("err", err)...)
+		}
+	}
+}
+
+// logFields returns the log fields identifying the request followed by
+// keyvals.
+func (c *CodecRequest) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"method", c.request.Method, "codec", ContentType, "id", c.request.Id, "remote", c.remote}
+	return append(fields, keyvals...)
+}
+
+// writeArrayResponse writes the response in the array framing. Notifications
+// don't have a response.
+func (c *CodecRequest) writeArrayResponse(w http.ResponseWriter, res *serverResponse) {
+	if c.notification {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/msgpack; charset=utf-8")
+
+	buffer, err := encodeArrayResponse(res)
+	if err != nil {
+		c.logger.Error("msgpack Encode", c.logFields("err", err)...)
+		rpcHttp.WriteError(w, 400, err.Error())
+		return
+	}
+	if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+		c.logger.Warn("write response", c.logFields("err", err)...)
 	}
 }
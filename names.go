@@ -0,0 +1,121 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MethodName is a method name read as a service name and a method name.
+type MethodName struct {
+	// The service name, empty for an unqualified method name.
+	Service string
+	// The method name.
+	Method string
+}
+
+// NameMapper controls how the names of services and methods are derived at
+// registration and how the method names sent by clients are parsed.
+type NameMapper interface {
+	// Name returns the exposed name of a service or a method from its Go
+	// name. It is not applied to names given explicitly.
+	Name(goName string) string
+	// Join returns the full name of a method of a service.
+	Join(service, method string) string
+	// Split returns the ways to read a full method name, in order of
+	// preference. An unqualified name is read with an empty service name.
+	// No readings means the name is ill-formed.
+	Split(fullName string) []MethodName
+}
+
+// DefaultNameMapper exposes the Go names as they are, in the dotted notation
+// "Service.Method".
+var DefaultNameMapper = NewNameMapper(".", nil)
+
+// NewNameMapper returns a NameMapper joining service and method names with
+// separator and deriving names with convert, e.g. SnakeCase. A nil convert
+// keeps the Go names.
+func NewNameMapper(separator string, convert func(string) string) NameMapper {
+	return &nameMapper{separator: separator, convert: convert}
+}
+
+type nameMapper struct {
+	separator string
+	convert   func(string) string
+}
+
+func (m *nameMapper) Name(goName string) string {
+	if m.convert == nil {
+		return goName
+	}
+	return m.convert(goName)
+}
+
+func (m *nameMapper) Join(service, method string) string {
+	return service + m.separator + method
+}
+
+// Split reads the name at every occurrence of the separator, so that names
+// containing the separator themselves can still be resolved.
+func (m *nameMapper) Split(fullName string) []MethodName {
+	if fullName == "" {
+		return nil
+	}
+	if !strings.Contains(fullName, m.separator) {
+		return []MethodName{{Method: fullName}}
+	}
+	var names []MethodName
+	for i := 0; i < len(fullName); {
+		idx := strings.Index(fullName[i:], m.separator)
+		if idx == -1 {
+			break
+		}
+		i += idx
+		service, method := fullName[:i], fullName[i+len(m.separator):]
+		if service != "" && method != "" {
+			names = append(names, MethodName{Service: service, Method: method})
+		}
+		i += len(m.separator)
+	}
+	return names
+}
+
+// SnakeCase converts a Go name to snake case, e.g. "GetUserID" to
+// "get_user_id".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// CamelCase converts a Go name to lower camel case, e.g. "GetUser" to
+// "getUser" and "HTTPStatus" to "httpStatus".
+func CamelCase(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			break
+		}
+		// Keep the last upper case letter of an acronym followed by a
+		// lower case letter, e.g. the "S" of "HTTPStatus".
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
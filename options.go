@@ -0,0 +1,210 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ServiceOption configures a service at registration time.
+type ServiceOption func(s *service) error
+
+// WithTimeout sets the timeout of every method of the service, overriding
+// the server default.
+func WithTimeout(timeout time.Duration) ServiceOption {
+	return func(s *service) error {
+		s.timeout = timeout
+		return nil
+	}
+}
+
+// WithMethodTimeout sets the timeout of a single method, overriding the
+// service and server defaults. The method is given by its Go name.
+func WithMethodTimeout(method string, timeout time.Duration) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		m.timeout = timeout
+		return nil
+	}
+}
+
+// WithMaxInFlight limits the number of calls running concurrently on the
+// methods of the service to maxInFlight, with up to queue calls waiting.
+func WithMaxInFlight(maxInFlight, queue int) ServiceOption {
+	return func(s *service) error {
+		s.limiter = newLimiter(maxInFlight, queue)
+		return nil
+	}
+}
+
+// WithMethodMaxInFlight limits the number of calls running concurrently on a
+// single method to maxInFlight, with up to queue calls waiting. The method is
+// given by its Go name.
+func WithMethodMaxInFlight(method string, maxInFlight, queue int) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		m.limiter = newLimiter(maxInFlight, queue)
+		return nil
+	}
+}
+
+// WithMethodName exposes a method under a new name instead of its Go name.
+func WithMethodName(method string, name string) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.methods[s.key(name)]; ok {
+			return fmt.Errorf("rpc: method already defined: %q", s.mapper.Join(s.name, name))
+		}
+		delete(s.methods, s.key(m.name))
+		m.name = name
+		s.methods[s.key(name)] = m
+		return nil
+	}
+}
+
+// WithMethodAlias makes a method callable under additional names. Aliases
+// are not listed by EnumMethod.
+func WithMethodAlias(method string, aliases ...string) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		for _, alias := range aliases {
+			if _, ok := s.methods[s.key(alias)]; ok {
+				return fmt.Errorf("rpc: method already defined: %q", s.mapper.Join(s.name, alias))
+			}
+			s.methods[s.key(alias)] = m
+		}
+		return nil
+	}
+}
+
+// WithHiddenMethod hides a method from EnumMethod, EnumMethodInfo and
+// MethodPage. The method can still be called.
+func WithHiddenMethod(method string) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		m.hidden = true
+		return nil
+	}
+}
+
+// WithDeprecatedMethod marks a method deprecated. Each call is logged with
+// the message, and the response carries a "Deprecation" header and the
+// message in a "Warning" header.
+func WithDeprecatedMethod(method string, message string) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		if message == "" {
+			message = "deprecated"
+		}
+		m.deprecated = message
+		return nil
+	}
+}
+
+// WithMethodNotifications allows or rejects notifications, the requests
+// expecting no response, for a method. Notifications are allowed by default;
+// a rejected notification is not executed and answered with HTTP status 400.
+func WithMethodNotifications(method string, allow bool) ServiceOption {
+	return func(s *service) error {
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		m.rejectNotifications = !allow
+		return nil
+	}
+}
+
+// WithPositionalParams registers the methods of the service taking one or
+// more parameters by position instead of *args, as in
+//
+//	func (h *HelloService) Greet(name string, times int, reply *string) error
+//
+// These methods are ignored otherwise. The option must come before the other
+// options applying to them.
+func WithPositionalParams() ServiceOption {
+	return func(s *service) error {
+		for _, m := range s.positional {
+			if err := s.registerPositional(m.goName); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithMethodParams registers a method taking parameters by position, as
+// WithPositionalParams does for all of them, and names its parameters for the
+// requests passing them by name. They are named "p0", "p1"... by default.
+func WithMethodParams(method string, names ...string) ServiceOption {
+	return func(s *service) error {
+		if err := s.registerPositional(method); err != nil {
+			return err
+		}
+		m, err := s.method(method)
+		if err != nil {
+			return err
+		}
+		if !m.params {
+			return fmt.Errorf("rpc: method %q does not take parameters by position", method)
+		}
+		if len(names) != m.argsType.NumField() {
+			return fmt.Errorf("rpc: method %q takes %d parameters, got %d names", method, m.argsType.NumField(), len(names))
+		}
+		types := make([]reflect.Type, len(names))
+		for i := range types {
+			types[i] = m.argsType.Field(i).Type
+		}
+		m.argsType = paramsType(types, names)
+		return nil
+	}
+}
+
+// registerPositional registers the method with the given Go name taking
+// parameters by position, if not registered yet.
+func (s *service) registerPositional(name string) error {
+	for key, m := range s.positional {
+		if m.goName != name {
+			continue
+		}
+		if _, ok := s.methods[key]; ok {
+			return fmt.Errorf("rpc: method already defined: %q", s.mapper.Join(s.name, m.name))
+		}
+		delete(s.positional, key)
+		s.methods[key] = m
+	}
+	return nil
+}
+
+// method returns the registered method with the given Go name.
+func (s *service) method(name string) (*serviceMethod, error) {
+	for _, m := range s.methods {
+		if m.goName == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("rpc: service %q has no method %q", s.name, name)
+}
@@ -0,0 +1,15 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+// PositionalCodecRequest is implemented by a CodecRequest that decodes the
+// params of the methods taking parameters by position in another way than
+// the args of the other methods, e.g. from a by-position array.
+type PositionalCodecRequest interface {
+	// Reads the request filling the args struct of a method taking
+	// parameters by position, with a field per parameter.
+	ReadPositionalRequest(interface{}) error
+}
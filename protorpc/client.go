@@ -0,0 +1,153 @@
+package protorpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+
+	"github.com/Limard/rpcHttp"
+	"github.com/golang/protobuf/proto"
+)
+
+var ContentType = `application/x-protobuf`
+
+// Client calls methods on protocol buffers RPC servers. It is safe for concurrent
+// use. Call and CallContext use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	logger     rpcHttp.Logger
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithClientLogger sets the logger of the client.
+func WithClientLogger(logger rpcHttp.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+func encodeClientRequest(method string, args proto.Message) ([]byte, error) {
+	params, err := proto.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	c := &request{
+		Method: method,
+		Params: params,
+		Id:     uint64(rand.Int63()),
+	}
+	return proto.Marshal(c)
+}
+
+func decodeClientResponse(r io.Reader, reply proto.Message) (e error) {
+	var c response
+	buf, err := ioutil.ReadAll(r)
+	if err == nil {
+		err = proto.Unmarshal(buf, &c)
+	}
+	if err != nil {
+		return &Error{
+			Code:    E_PARSE,
+			Message: err.Error()}
+	}
+
+	// Error
+	if c.Error != nil {
+		return &Error{
+			Code:    int(c.Error.Code),
+			Message: c.Error.Message,
+			Data:    c.Error.Data,
+		}
+	}
+
+	// Result, empty for a message with default values only.
+	if err := proto.Unmarshal(c.Result, reply); err != nil {
+		return &Error{
+			Code:    E_PARSE,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func Call(url string, method string, request proto.Message, reply proto.Message) (e error) {
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+// CallContext calls method with client. The deadline of ctx, if any, is sent
+// to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request proto.Message, reply proto.Message) (e error) {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (c *Client) Call(url string, method string, request proto.Message, reply proto.Message) error {
+	return c.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (c *Client) CallContext(ctx context.Context, url string, method string, request proto.Message, reply proto.Message) error {
+	fields := []interface{}{"method", method, "codec", ContentType, "remote", url}
+	reqBuf, err := encodeClientRequest(method, request)
+	if err != nil {
+		c.logger.Error("encodeClientRequest", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBuf))
+	if err != nil {
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("http.Post", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_SERVER,
+			Message: err.Error()}
+	}
+	defer rsp.Body.Close()
+
+	err = decodeClientResponse(rsp.Body, reply)
+	if err != nil {
+		// The errors of the server are returned as is.
+		if replyErr, ok := err.(*Error); !ok || replyErr.Code == E_PARSE {
+			c.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+		}
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package protorpc
+
+import "encoding/json"
+
+const (
+	E_PARSE       = -32700
+	E_INVALID_REQ = -32600
+	E_NO_METHOD   = -32601
+	E_BAD_PARAMS  = -32602
+	E_INTERNAL    = -32603
+	E_SERVER      = -32000
+	E_TIMEOUT     = -32001
+	E_BUSY        = -32002
+)
+
+// Error is the error of a response. Data holds the text of the data of the
+// error, if any.
+type Error struct {
+	Code    int    `json:"code"`           /* required */
+	Message string `json:"message"`        /* required */
+	Data    string `json:"data,omitempty"` /* optional */
+}
+
+func (e *Error) Error() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
@@ -0,0 +1,55 @@
+package protorpc
+
+import "github.com/golang/protobuf/proto"
+
+// The envelope messages, as described by:
+//
+//	message Request {
+//	  string method = 1;
+//	  bytes params = 2;
+//	  uint64 id = 3;
+//	}
+//
+//	message Response {
+//	  uint64 id = 1;
+//	  bytes result = 2;
+//	  Error error = 3;
+//	}
+//
+//	message Error {
+//	  int32 code = 1;
+//	  string message = 2;
+//	  string data = 3;
+//	}
+//
+// params and result hold the encoded args and reply messages.
+
+type request struct {
+	Method string `protobuf:"bytes,1,opt,name=method,proto3"`
+	Params []byte `protobuf:"bytes,2,opt,name=params,proto3"`
+	Id     uint64 `protobuf:"varint,3,opt,name=id,proto3"`
+}
+
+func (m *request) Reset()         { *m = request{} }
+func (m *request) String() string { return proto.CompactTextString(m) }
+func (*request) ProtoMessage()    {}
+
+type response struct {
+	Id     uint64        `protobuf:"varint,1,opt,name=id,proto3"`
+	Result []byte        `protobuf:"bytes,2,opt,name=result,proto3"`
+	Error  *errorMessage `protobuf:"bytes,3,opt,name=error,proto3"`
+}
+
+func (m *response) Reset()         { *m = response{} }
+func (m *response) String() string { return proto.CompactTextString(m) }
+func (*response) ProtoMessage()    {}
+
+type errorMessage struct {
+	Code    int32  `protobuf:"varint,1,opt,name=code,proto3"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3"`
+	Data    string `protobuf:"bytes,3,opt,name=data,proto3"`
+}
+
+func (m *errorMessage) Reset()         { *m = errorMessage{} }
+func (m *errorMessage) String() string { return proto.CompactTextString(m) }
+func (*errorMessage) ProtoMessage()    {}
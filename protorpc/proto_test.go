@@ -0,0 +1,169 @@
+package protorpc
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Limard/rpcHttp"
+	"github.com/Limard/rpcHttp/jsonrpc2"
+	"github.com/golang/protobuf/proto"
+)
+
+var ErrResponseError = errors.New("response error")
+
+// Messages as generated from:
+//
+//	message Service1Request {
+//	  int64 a = 1;
+//	  int64 b = 2;
+//	}
+//
+//	message Service1Response {
+//	  int64 result = 1;
+//	}
+
+type Service1Request struct {
+	A int64 `protobuf:"varint,1,opt,name=a,proto3"`
+	B int64 `protobuf:"varint,2,opt,name=b,proto3"`
+}
+
+func (m *Service1Request) Reset()         { *m = Service1Request{} }
+func (m *Service1Request) String() string { return proto.CompactTextString(m) }
+func (*Service1Request) ProtoMessage()    {}
+
+type Service1Response struct {
+	Result int64 `protobuf:"varint,1,opt,name=result,proto3"`
+}
+
+func (m *Service1Response) Reset()         { *m = Service1Response{} }
+func (m *Service1Response) String() string { return proto.CompactTextString(m) }
+func (*Service1Response) ProtoMessage()    {}
+
+type Service1 struct {
+}
+
+func (t *Service1) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A * req.B
+	return nil
+}
+
+func (t *Service1) ResponseError(r *http.Request, req *Service1Request, res *Service1Response) error {
+	return ErrResponseError
+}
+
+type Service2Request struct {
+	A int
+}
+
+type Service2 struct {
+}
+
+func (t *Service2) Negate(r *http.Request, req *Service2Request, res *Service1Response) error {
+	res.Result = int64(-req.A)
+	return nil
+}
+
+func TestService(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	if err := s.RegisterService(new(Service1), ""); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var res Service1Response
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d, %v", res.Result, err)
+	}
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 0}, &res); err != nil || res.Result != 0 {
+		t.Errorf("Expected 0, got %d, %v", res.Result, err)
+	}
+	err := Call(server.URL, "Service1.ResponseError", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_SERVER || replyErr.Message != ErrResponseError.Error() {
+		t.Errorf("Expected error %d %q, got %v", E_SERVER, ErrResponseError, err)
+	}
+	if err := Call(server.URL, "Service1.Unknown", &Service1Request{4, 2}, &res); err == nil {
+		t.Errorf("Expected an error for an unknown method")
+	}
+
+	// Request{method: "Service1.Multiply", params: {a: 4, b: 2}, id: 1}
+	body := []byte("\x0a\x11Service1.Multiply\x12\x04\x08\x04\x10\x02\x18\x01")
+	rsp, err := http.Post(server.URL, ContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(rsp.Body)
+	// Response{id: 1, result: {result: 8}}
+	if want := []byte("\x08\x01\x12\x02\x08\x08"); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Wrong response: got % x, want % x", buf.Bytes(), want)
+	}
+}
+
+func TestValidateMethod(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	err := s.RegisterService(new(Service2), "")
+	if err == nil || !strings.Contains(err.Error(), "Service2.Negate") || !strings.Contains(err.Error(), "Service2Request is not a proto.Message") {
+		t.Errorf("Expected an error for Service2.Negate, got %v", err)
+	}
+	err = s.RegisterFunc("Func.Negate", new(Service2).Negate)
+	if err == nil || !strings.Contains(err.Error(), "Func.Negate") {
+		t.Errorf("Expected an error for Func.Negate, got %v", err)
+	}
+
+	// A server of several codecs checks the methods per call.
+	s = rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	s.RegisterCodec(jsonrpc2.NewCodec(), jsonrpc2.ContentType)
+	// A service of other types is registered for the other codecs.
+	if err := s.RegisterService(new(Service2), ""); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var res Service1Response
+	err = Call(server.URL, "Service2.Negate", &Service1Request{}, &res)
+	if err == nil || !strings.Contains(err.Error(), "Service2Request is not a proto.Message") {
+		t.Errorf("Expected an unsupported method error, got %v", err)
+	}
+	var jsonRes struct{ Result int64 }
+	if err := jsonrpc2.Call(server.URL, "Service2.Negate", &Service2Request{A: 4}, &jsonRes); err != nil || jsonRes.Result != -4 {
+		t.Errorf("Expected -4, got %d, %v", jsonRes.Result, err)
+	}
+
+	// The option checks the methods at registration.
+	err = s.RegisterServiceWithOptions(new(Service2), "Checked", rpcHttp.WithMethodValidator(NewCodec()))
+	if err == nil || !strings.Contains(err.Error(), "Checked.Negate") || !strings.Contains(err.Error(), "Service2Request") {
+		t.Errorf("Expected an error for Checked.Negate, got %v", err)
+	}
+	if err := s.RegisterServiceWithOptions(new(Service1), "Checked", rpcHttp.WithMethodValidator(NewCodec())); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("oops"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewClient(WithHTTPClient(server.Client()), WithClientLogger(rpcHttp.NewStdLogger(log.New(&buf, "", 0))))
+	var res Service1Response
+	err := client.Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_PARSE {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "level=warn msg=decodeClientResponse method=Service1.Multiply") {
+		t.Errorf("Wrong log: %q", buf.String())
+	}
+}
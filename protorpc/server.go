@@ -0,0 +1,190 @@
+package protorpc
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+
+	"github.com/Limard/rpcHttp"
+	"github.com/golang/protobuf/proto"
+)
+
+var messageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{encSel: rpcHttp.DefaultEncoderSelector, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithLogger sets the logger of the codec.
+func WithLogger(logger rpcHttp.Logger) CodecOption {
+	return func(c *Codec) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// Codec creates a CodecRequest to process each request.
+//
+// The args and reply of the methods must be protocol buffers messages. On a
+// server with no other codec, registering a service with other methods fails.
+// Otherwise the calls to those methods through this codec are answered with an
+// error, so that the services of a server may also use other codecs, and the
+// rpcHttp.WithMethodValidator option checks the methods of a service at
+// registration:
+//
+//	s.RegisterServiceWithOptions(new(UserService), "", rpcHttp.WithMethodValidator(codec))
+type Codec struct {
+	encSel rpcHttp.EncoderSelector
+	logger rpcHttp.Logger
+}
+
+// ValidateMethod implements rpcHttp.MethodValidator. It fails if the args or
+// the reply of the method are not proto.Message.
+func (c *Codec) ValidateMethod(method string, argsType, replyType reflect.Type) error {
+	if !reflect.PtrTo(argsType).Implements(messageType) {
+		return fmt.Errorf("args type %s is not a proto.Message", argsType)
+	}
+	if !reflect.PtrTo(replyType).Implements(messageType) {
+		return fmt.Errorf("reply type %s is not a proto.Message", replyType)
+	}
+	return nil
+}
+
+func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
+	var err error
+	req := new(request)
+	buf, e := ioutil.ReadAll(r.Body)
+	if e == nil {
+		e = proto.Unmarshal(buf, req)
+	}
+	if e != nil {
+		err = &Error{
+			Code:    E_PARSE,
+			Message: e.Error(),
+		}
+	}
+	r.Body.Close()
+	return &CodecRequest{
+		request: req,
+		err:     err,
+		encoder: c.encSel.Select(r),
+		logger:  c.logger,
+		remote:  r.RemoteAddr,
+	}
+}
+
+type CodecRequest struct {
+	request *request
+	err     error
+	encoder rpcHttp.Encoder
+	logger  rpcHttp.Logger
+	remote  string
+}
+
+func (c *CodecRequest) Method() (string, error) {
+	if c.err == nil {
+		return c.request.Method, nil
+	}
+	return "", c.err
+}
+
+// RequestID returns the id of the current request.
+func (c *CodecRequest) RequestID() interface{} {
+	return c.request.Id
+}
+
+// ReadRequest decodes the params message into args.
+func (c *CodecRequest) ReadRequest(args interface{}) error {
+	if c.err == nil {
+		msg, ok := args.(proto.Message)
+		var err error
+		if !ok {
+			err = fmt.Errorf("args type %T is not a proto.Message", args)
+		} else if err = proto.Unmarshal(c.request.Params, msg); err != nil {
+			c.logger.Warn("invalid params", c.logFields("err", err)...)
+		}
+		if err != nil {
+			c.err = &Error{
+				Code:    E_INVALID_REQ,
+				Message: err.Error(),
+			}
+		}
+	}
+	return c.err
+}
+
+// WriteResponse encodes the reply message and writes the response to the
+// ResponseWriter.
+func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}) {
+	msg, ok := reply.(proto.Message)
+	if !ok {
+		c.WriteErrorResponse(w, E_INTERNAL, fmt.Errorf("reply type %T is not a proto.Message", reply), nil)
+		return
+	}
+	result, err := proto.Marshal(msg)
+	if err != nil {
+		c.logger.Error("protobuf Encode", c.logFields("err", err)...)
+		c.WriteErrorResponse(w, E_INTERNAL, err, nil)
+		return
+	}
+	res := &response{
+		Id:     c.request.Id,
+		Result: result,
+	}
+	c.writeServerResponse(w, res)
+}
+
+func (c *CodecRequest) WriteErrorResponse(w http.ResponseWriter, code int, err error, data interface{}) {
+	objErr := &Error{}
+	if !errors.As(err, &objErr) {
+		objErr = &Error{
+			Code:    code,
+			Message: err.Error(),
+		}
+		if data != nil {
+			objErr.Data = fmt.Sprint(data)
+		}
+	}
+	res := &response{
+		Id: c.request.Id,
+		Error: &errorMessage{
+			Code:    int32(objErr.Code),
+			Message: objErr.Message,
+			Data:    objErr.Data,
+		},
+	}
+	c.writeServerResponse(w, res)
+}
+
+func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, res *response) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", ContentType)
+
+	buffer, err := proto.Marshal(res)
+	if err != nil {
+		c.logger.Error("protobuf Encode", c.logFields("err", err)...)
+		rpcHttp.WriteError(w, 400, err.Error())
+		return
+	}
+	if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+		c.logger.Warn("write response", c.logFields("err", err)...)
+	}
+}
+
+// logFields returns the log fields identifying the request followed by
+// keyvals.
+func (c *CodecRequest) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"method", c.request.Method, "codec", ContentType, "id", c.request.Id, "remote", c.remote}
+	return append(fields, keyvals...)
+}
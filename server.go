@@ -6,11 +6,16 @@
 package rpcHttp
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"reflect"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // ----------------------------------------------------------------------------
@@ -42,6 +47,8 @@ const (
 	E_BAD_PARAMS  = -32602
 	E_INTERNAL    = -32603
 	E_SERVER      = -32000
+	E_TIMEOUT     = -32001
+	E_BUSY        = -32002
 )
 
 // ----------------------------------------------------------------------------
@@ -50,21 +57,38 @@ const (
 
 // NewServer returns a new RPC server.
 func NewServer() *Server {
-	return &Server{
+	s := &Server{
 		codecs:         make(map[string]Codec),
 		services:       new(serviceMap),
 		postMethodOnly: true,
+		logger:         NopLogger,
 	}
+	s.discover = s.newDiscoverService()
+	return s
 }
 
 // Server serves registered RPC services using registered codecs.
 type Server struct {
-	codecs           map[string]Codec
-	services         *serviceMap
-	methodIgnoreCase bool
-	postMethodOnly   bool
+	codecs             map[string]Codec
+	services           *serviceMap
+	methodIgnoreCase   bool
+	postMethodOnly     bool
+	interceptors       []Interceptor
+	panicHandler       PanicHandler
+	debug              bool
+	timeout            time.Duration
+	limiter            *limiter
+	logger             Logger
+	batchConcurrency   int
+	asyncNotifications bool
+	discoverInfo       OpenRPCInfo
+	discover           *service // built-in service of rpc.discover
 }
 
+// PanicHandler is called with the call, the recovered value and the stack
+// when a service method panics.
+type PanicHandler func(call *CallInfo, v interface{}, stack []byte)
+
 func (s *Server) SetPostMethodOnly(postMethodOnly bool) {
 	s.postMethodOnly = postMethodOnly
 }
@@ -74,13 +98,94 @@ func (s *Server) SetMethodIgnoreCase(ignoreCase bool) {
 	s.services.methodIgnoreCase = ignoreCase
 }
 
+// SetLogger sets the logger of the server. The default logger discards
+// everything; use NewStdLogger to write to the standard log package.
+func (s *Server) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger
+	}
+	s.logger = logger
+}
+
+// SetPanicHandler sets a function called when a call panics, in the service
+// method, an interceptor or the codec, e.g. to report the panic. The client
+// receives an E_INTERNAL error in any case.
+func (s *Server) SetPanicHandler(handler PanicHandler) {
+	s.panicHandler = handler
+}
+
+// SetDebug includes the value and the stack of a recovered panic in the error
+// sent to the client, which otherwise only gets "rpc: internal error". It
+// should not be enabled in production.
+func (s *Server) SetDebug(debug bool) {
+	s.debug = debug
+}
+
+// SetTimeout sets the default timeout of the method calls. A call running
+// longer is answered with an E_TIMEOUT error and its context is canceled;
+// zero means no timeout. The timeout can be overridden per service or per
+// method with the WithTimeout and WithMethodTimeout options.
+//
+// The method keeps running after the timeout until it returns, so it should
+// watch its context. A method taking an http.ResponseWriter writes to a
+// buffer, copied to the response only if the method returns in time.
+func (s *Server) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+// SetMaxInFlight limits the number of calls running concurrently on the
+// server to maxInFlight, with up to queue calls waiting for a slot. Calls
+// beyond are answered with an E_BUSY error and HTTP status 503 when the codec
+// writes one. Zero means no limit. Limits can also be set per service or per
+// method with the WithMaxInFlight and WithMethodMaxInFlight options.
+func (s *Server) SetMaxInFlight(maxInFlight, queue int) {
+	s.limiter = newLimiter(maxInFlight, queue)
+}
+
+// SetAsyncNotifications runs the notifications, the requests expecting no
+// response, after the response is sent instead of before. Their context is
+// then not canceled when the client connection closes.
+func (s *Server) SetAsyncNotifications(async bool) {
+	s.asyncNotifications = async
+}
+
+// SetBatchConcurrency sets the number of requests of a batch served in
+// parallel. Batches are served sequentially by default.
+func (s *Server) SetBatchConcurrency(n int) {
+	s.batchConcurrency = n
+}
+
+// Use appends interceptors to the chain run around every method call.
+//
+// Interceptors run in registration order: the first one registered is the
+// outermost and sees the call first.
+func (s *Server) Use(interceptors ...Interceptor) {
+	s.interceptors = append(s.interceptors, interceptors...)
+}
+
+// SetNameMapper sets how the names of services and methods are derived at
+// registration and how the method names sent by clients are parsed, e.g.
+//
+//	s.SetNameMapper(rpc.NewNameMapper("/", rpc.SnakeCase))
+//
+// exposes the method GetUser of the service UserService as
+// "user_service/get_user". It must be called before registering services.
+func (s *Server) SetNameMapper(mapper NameMapper) {
+	s.services.mapper = mapper
+}
+
 // RegisterCodec adds a new codec to the server.
 //
 // Codecs are defined to process a given serialization scheme, e.g., JSON or
 // XML. A codec is chosen based on the "Content-Type" header from the request,
 // excluding the charset definition.
+//
+// Codecs should be registered before the services: a service fails to
+// register if none of the codecs supports one of its methods, see
+// MethodValidator.
 func (s *Server) RegisterCodec(codec Codec, contentType string) {
 	s.codecs[strings.ToLower(contentType)] = codec
+	s.services.validator = newCodecValidator(s.codecs)
 }
 
 // RegisterService adds a new service to the server.
@@ -90,22 +195,59 @@ func (s *Server) RegisterCodec(codec Codec, contentType string) {
 //
 // Methods from the receiver will be extracted if these rules are satisfied:
 //
-//    - The receiver is exported (begins with an upper case letter) or local
-//      (defined in the package registering the service).
-//    - The method name is exported.
-//    - The method has three arguments: *http.Request, *args, *reply.
-//    - All three arguments are pointers.
-//    - The second and third arguments are exported or local.
-//    - The method has return type error.
+//   - The receiver is exported (begins with an upper case letter) or local
+//     (defined in the package registering the service).
+//   - The method name is exported.
+//   - The method has two arguments *args, *reply, optionally preceded by
+//     either context.Context or *http.Request (and http.ResponseWriter).
+//   - The *args and *reply arguments are pointers.
+//   - The *args and *reply arguments are exported or local.
+//   - The method has return type error.
 //
-// All other methods are ignored.
+// All other methods are ignored, as are the methods taking parameters by
+// position unless registered with the WithPositionalParams option.
 func (s *Server) RegisterService(receiver interface{}, name string) error {
 	return s.services.register(receiver, name)
 }
 
+// RegisterServiceWithOptions adds a new service to the server like
+// RegisterService, then applies the options to it.
+func (s *Server) RegisterServiceWithOptions(receiver interface{}, name string, opts ...ServiceOption) error {
+	return s.services.register(receiver, name, opts...)
+}
+
+// RegisterFunc adds a standalone function or closure to the server under the
+// given name, as in "Service.Method" with the default name mapper.
+//
+// The function must follow the rules of the service methods, without the
+// receiver, and may take parameters by position. Functions registered under
+// the same service name are grouped in a service of their own, which must not
+// also be registered with RegisterService. The name is split at the first
+// separator of the name mapper.
+func (s *Server) RegisterFunc(name string, fn interface{}) error {
+	return s.services.registerFunc(name, fn)
+}
+
+// UnregisterService removes a registered service from the server. Calls in
+// flight finish normally; new calls fail as for an unknown service.
+func (s *Server) UnregisterService(name string) error {
+	return s.services.unregister(name)
+}
+
+// ReplaceService atomically replaces a registered service with a new
+// receiver. The name is required or inferred as in RegisterService, and the
+// options of the old service are not carried over.
+//
+// Calls in flight finish on the old receiver while new calls go to the new
+// one.
+func (s *Server) ReplaceService(receiver interface{}, name string, opts ...ServiceOption) error {
+	return s.services.replace(receiver, name, opts...)
+}
+
 // HasMethod returns true if the given method is registered.
 //
-// The method uses a dotted notation as in "Service.Method".
+// The method uses a dotted notation as in "Service.Method", or the notation
+// of the name mapper.
 func (s *Server) HasMethod(method string) bool {
 	if _, _, err := s.services.get(method); err == nil {
 		return true
@@ -133,10 +275,11 @@ func (s *Server) MethodPage(w http.ResponseWriter, r *http.Request) {
 
 // ServeHTTP
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer s.recoverRequest(w, r)
 
 	if s.postMethodOnly {
 		if r.Method != "POST" {
-			log.Printf("POST method required, Current: %s", r.Method)
+			s.logger.Warn("POST method required", "httpMethod", r.Method, "remote", r.RemoteAddr)
 			WriteError(w, 405, "rpc: POST method required, received "+r.Method)
 			return
 		}
@@ -155,53 +298,263 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			codec = c
 		}
 	} else if codec = s.codecs[strings.ToLower(contentType)]; codec == nil {
-		log.Printf("unrecognized Content-Type(%s)", contentType)
+		s.logger.Warn("unrecognized Content-Type", "codec", contentType, "remote", r.RemoteAddr)
 		WriteError(w, 415, "rpc: unrecognized Content-Type: "+contentType)
 		return
 	}
 	// Create a new codec request.
 	codecReq := codec.NewRequest(r)
+	if batchReq, ok := codecReq.(BatchCodecRequest); ok {
+		if requests, isBatch := batchReq.Batch(); isBatch {
+			s.serveBatch(w, r, codec, contentType, batchReq, requests)
+			return
+		}
+	}
+	s.serveRequest(w, r, codec, contentType, codecReq)
+}
+
+// serveRequest serves a single request of a codec.
+func (s *Server) serveRequest(w http.ResponseWriter, r *http.Request, codec Codec, contentType string, codecReq CodecRequest) {
+	var id interface{}
+	if identifier, ok := codecReq.(RequestIdentifier); ok {
+		id = identifier.RequestID()
+	}
 	// Get service method to be called.
 	method, errMethod := codecReq.Method()
+	fields := []interface{}{"method", method, "codec", contentType, "id", id, "remote", r.RemoteAddr}
 	if errMethod != nil {
-		log.Println("errMethod", errMethod)
+		s.logger.Warn("errMethod", append(fields, "err", errMethod)...)
 		codecReq.WriteErrorResponse(w, 400, errMethod, nil)
 		return
 	}
-	serviceSpec, methodSpec, errGet := s.services.get(method)
+	serviceSpec, methodSpec, errGet := s.lookup(method)
 	if errGet != nil {
-		log.Println("errGet", errGet)
+		s.logger.Warn("errGet", append(fields, "err", errGet)...)
 		codecReq.WriteErrorResponse(w, 400, errGet, nil)
 		return
 	}
+	if validator, ok := codec.(MethodValidator); ok {
+		if err := validator.ValidateMethod(method, methodSpec.argsType, methodSpec.replyType); err != nil {
+			err = fmt.Errorf("rpc: method %q not supported by the %s codec: %v", method, contentType, err)
+			s.logger.Warn("errValidate", append(fields, "err", err)...)
+			codecReq.WriteErrorResponse(w, 400, err, nil)
+			return
+		}
+	}
+	if methodSpec.deprecated != "" {
+		s.logger.Warn("deprecated method called", append(fields, "message", methodSpec.deprecated)...)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Warning", "299 - "+strconv.Quote(methodSpec.deprecated))
+	}
+	notification := false
+	if notifier, ok := codecReq.(NotificationCodecRequest); ok {
+		notification = notifier.IsNotification()
+	}
+	if notification && methodSpec.rejectNotifications {
+		err := fmt.Errorf("rpc: notifications not allowed for %q", method)
+		s.logger.Warn("errNotification", append(fields, "err", err)...)
+		w = &statusWriter{ResponseWriter: w, status: http.StatusBadRequest}
+		codecReq.WriteErrorResponse(w, E_INVALID_REQ, err, nil)
+		return
+	}
 	// Decode the args.
 	args := reflect.New(methodSpec.argsType)
-	if errRead := codecReq.ReadRequest(args.Interface()); errRead != nil {
-		log.Println("errRead", errRead)
+	var errRead error
+	if reader, ok := codecReq.(PositionalCodecRequest); ok && methodSpec.params {
+		errRead = reader.ReadPositionalRequest(args.Interface())
+	} else {
+		errRead = codecReq.ReadRequest(args.Interface())
+	}
+	if errRead != nil {
+		s.logger.Warn("errRead", append(fields, "err", errRead)...)
 		codecReq.WriteErrorResponse(w, 400, errRead, nil)
 		return
 	}
-	// Call the service method.
 	reply := reflect.New(methodSpec.replyType)
-	methodSpec.counter++
+	atomic.AddInt64(&methodSpec.counter, 1)
 
+	// The context is canceled when the client connection closes, the call
+	// times out or returns. The call of an asynchronous notification outlives
+	// the HTTP request, so its context is not tied to the connection.
+	async := notification && s.asyncNotifications
+	base := r.Context()
+	if async {
+		base = detachedContext{base}
+	}
+	name := s.services.nameMapper().Join(serviceSpec.name, methodSpec.name)
+	if serviceSpec == s.discover {
+		name = DiscoverMethod
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	timeout := s.callTimeout(r, serviceSpec, methodSpec)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(base, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(base)
+	}
+	ctx = newCallContext(ctx, r, name, id)
+
+	call := &CallInfo{
+		Service: serviceSpec.name,
+		Method:  name,
+		Request: r,
+		Args:    args.Interface(),
+		Reply:   reply.Interface(),
+	}
+	methodWriter := w
+	var deadline *deadlineWriter
+	if async {
+		// The response is sent before the method runs.
+		methodWriter = newResponseBuffer()
+	} else if timeout > 0 && methodSpec.hasHttpRes {
+		// The method may still run when the response is sent.
+		deadline = newDeadlineWriter()
+		methodWriter = deadline
+	}
+	// Call the service method through the interceptors.
+	handler := func(ctx context.Context, call *CallInfo) (int, error, interface{}) {
+		errCode, errResult, errData := s.callMethod(ctx, methodSpec, methodWriter, call)
+		if deadline != nil {
+			deadline.markReturned()
+		}
+		return errCode, errResult, errData
+	}
+	handler = s.withLimits(serviceSpec, methodSpec, handler)
+	if timeout > 0 {
+		// The method runs in a goroutine of its own.
+		handler = withDeadline(s.withRecover(handler))
+	}
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		handler = chainInterceptor(s.interceptors[i], handler)
+	}
+	handler = s.withRecover(handler)
+	if async {
+		go func() {
+			defer cancel()
+			if errCode, errResult, _ := handler(ctx, call); errResult != nil {
+				s.logger.Info("notification err", append(fields, "code", errCode, "err", errResult)...)
+			}
+		}()
+		codecReq.WriteResponse(w, nil)
+		return
+	}
+	defer cancel()
+	errCode, errResult, errData := handler(ctx, call)
+	if deadline != nil {
+		deadline.flush(w)
+	}
+
+	// Prevents Internet Explorer from MIME-sniffing a response away
+	// from the declared content-type
+	w.Header().Set("x-content-type-options", "nosniff")
+	// Encode the response.
+	if errResult == nil {
+		// success response
+		codecReq.WriteResponse(w, call.Reply)
+		return
+	}
+	// error response
+	s.logger.Info("write err", append(fields, "code", errCode, "err", errResult)...)
+	if errResult == ErrServerBusy {
+		w = &statusWriter{ResponseWriter: w, status: http.StatusServiceUnavailable}
+	}
+	codecReq.WriteErrorResponse(w, errCode, errResult, errData)
+}
+
+// lookup returns the service and the method called by a request, including
+// the built-in rpc.discover.
+func (s *Server) lookup(method string) (*service, *serviceMethod, error) {
+	if method == DiscoverMethod {
+		return s.discover, s.discover.methods[DiscoverMethod], nil
+	}
+	return s.services.get(method)
+}
+
+// statusWriter is a http.ResponseWriter forcing the status of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(w.status)
+	return w.ResponseWriter.Write(b)
+}
+
+// withRecover wraps next so that a panic, in the interceptors or the method,
+// is returned as an E_INTERNAL error.
+func (s *Server) withRecover(next Handler) Handler {
+	return func(ctx context.Context, call *CallInfo) (errCode int, errResult error, errData interface{}) {
+		defer func() {
+			if v := recover(); v != nil {
+				errResult, errData = s.panicError(call, v, debug.Stack())
+				errCode = E_INTERNAL
+			}
+		}()
+		return next(ctx, call)
+	}
+}
+
+// recoverRequest recovers a panic out of the method call, e.g. in the codec,
+// and answers with HTTP status 500 since the codec may be broken. It must be
+// deferred.
+func (s *Server) recoverRequest(w http.ResponseWriter, r *http.Request) {
+	if v := recover(); v != nil {
+		err, _ := s.panicError(&CallInfo{Request: r}, v, debug.Stack())
+		WriteError(w, 500, err.Error())
+	}
+}
+
+// panicError reports a recovered panic and returns the error and the error
+// data sent to the client. The details are only sent in debug mode.
+func (s *Server) panicError(call *CallInfo, v interface{}, stack []byte) (error, interface{}) {
+	s.logger.Error("panic", "method", call.Method, "remote", call.Request.RemoteAddr, "panic", v)
+	if s.panicHandler != nil {
+		s.panicHandler(call, v, stack)
+	}
+	if !s.debug {
+		return errors.New("rpc: internal error"), nil
+	}
+	if call.Method == "" {
+		return fmt.Errorf("rpc: panic: %v", v), string(stack)
+	}
+	return fmt.Errorf("rpc: panic in %s: %v", call.Method, v), string(stack)
+}
+
+// callMethod calls the service method with the args and reply of call.
+func (s *Server) callMethod(ctx context.Context, methodSpec *serviceMethod, w http.ResponseWriter, call *CallInfo) (errCode int, errResult error, errData interface{}) {
 	params := make([]reflect.Value, 0)
-	params = append(params, serviceSpec.rcvr)
-	if methodSpec.hasHttpReq {
-		params = append(params, reflect.ValueOf(r))
+	if methodSpec.hasContext {
+		params = append(params, reflect.ValueOf(ctx))
+	} else if methodSpec.hasHttpReq {
+		params = append(params, reflect.ValueOf(call.Request))
 		if methodSpec.hasHttpRes {
 			params = append(params, reflect.ValueOf(w))
 		}
 	}
-	params = append(params, args)
-	params = append(params, reply)
+	if methodSpec.params {
+		args := reflect.ValueOf(call.Args).Elem()
+		for i := 0; i < args.NumField(); i++ {
+			params = append(params, args.Field(i))
+		}
+	} else {
+		params = append(params, reflect.ValueOf(call.Args))
+	}
+	params = append(params, reflect.ValueOf(call.Reply))
 
-	resValue := methodSpec.method.Func.Call(params)
+	resValue := methodSpec.fn.Call(params)
 
 	// Cast the result to error if needed.
-	errCode := E_SERVER
-	var errResult error
-	var errData interface{}
+	errCode = E_SERVER
 	if len(resValue) == 1 {
 		errInter := resValue[0].Interface()
 		if errInter != nil {
@@ -221,19 +574,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		errData = resValue[2].Interface()
 	}
-
-	// Prevents Internet Explorer from MIME-sniffing a response away
-	// from the declared content-type
-	w.Header().Set("x-content-type-options", "nosniff")
-	// Encode the response.
-	if errResult == nil {
-		// success response
-		codecReq.WriteResponse(w, reply.Interface())
-		return
-	}
-	// error response
-	log.Println("write err:", errResult)
-	codecReq.WriteErrorResponse(w, errCode, errResult, errData)
+	return errCode, errResult, errData
 }
 
 func WriteError(w http.ResponseWriter, status int, msg string) {
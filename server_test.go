@@ -6,9 +6,16 @@
 package rpcHttp
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 type Service1Request struct {
@@ -161,3 +168,697 @@ func TestServeHTTP(t *testing.T) {
 		t.Errorf("Response body was %s, should be %s.", w.Body, strconv.Itoa(expected))
 	}
 }
+
+type ServiceContext struct {
+	method string
+	req    *http.Request
+}
+
+func (t *ServiceContext) Multiply(ctx context.Context, req *Service1Request, res *Service1Response) error {
+	t.method = MethodFromContext(ctx)
+	t.req = RequestFromContext(ctx)
+	res.Result = req.A * req.B
+	return nil
+}
+
+func TestServeHTTPContext(t *testing.T) {
+	s := NewServer()
+	service := new(ServiceContext)
+	if err := s.RegisterService(service, "Service1"); err != nil {
+		t.Fatal(err)
+	}
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Body != "6" {
+		t.Errorf("Response body was %s, should be 6.", w.Body)
+	}
+	if service.method != "Service1.Multiply" {
+		t.Errorf("Method from context was %q, should be Service1.Multiply.", service.method)
+	}
+	if service.req != r {
+		t.Errorf("Request from context was not the HTTP request.")
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	s := NewServer()
+	s.RegisterService(new(Service1), "")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	var order []string
+	s.Use(func(ctx context.Context, call *CallInfo, next Handler) (int, error, interface{}) {
+		order = append(order, "first:"+call.Method)
+		code, err, data := next(ctx, call)
+		order = append(order, "reply:"+strconv.Itoa(call.Reply.(*Service1Response).Result))
+		return code, err, data
+	})
+	s.Use(func(ctx context.Context, call *CallInfo, next Handler) (int, error, interface{}) {
+		order = append(order, "second")
+		call.Args.(*Service1Request).B = 5
+		return next(ctx, call)
+	})
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Body != "10" {
+		t.Errorf("Response body was %s, should be 10.", w.Body)
+	}
+	if got := strings.Join(order, ","); got != "first:Service1.Multiply,second,reply:10" {
+		t.Errorf("Interceptors ran as %q.", got)
+	}
+
+	// Short-circuit the call.
+	s.Use(func(ctx context.Context, call *CallInfo, next Handler) (int, error, interface{}) {
+		return 403, errors.New("forbidden"), nil
+	})
+	w = NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != 403 || w.Body != "forbidden" {
+		t.Errorf("Response was %d %s, should be 403 forbidden.", w.Status, w.Body)
+	}
+}
+
+type ServicePanic struct {
+}
+
+func (t *ServicePanic) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	panic("boom")
+}
+
+func TestServeHTTPPanic(t *testing.T) {
+	s := NewServer()
+	s.RegisterService(new(ServicePanic), "Service1")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	var recovered interface{}
+	var method string
+	s.SetPanicHandler(func(call *CallInfo, v interface{}, stack []byte) {
+		recovered, method = v, call.Method
+	})
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != E_INTERNAL {
+		t.Errorf("Error code was %d, should be %d.", w.Status, E_INTERNAL)
+	}
+	if w.Body != "rpc: internal error" {
+		t.Errorf("Wrong response body: %s", w.Body)
+	}
+	if recovered != "boom" || method != "Service1.Multiply" {
+		t.Errorf("Panic handler got %v in %q.", recovered, method)
+	}
+
+	// The details are only sent in debug mode.
+	s.SetDebug(true)
+	w = NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Body != "rpc: panic in Service1.Multiply: boom" {
+		t.Errorf("Wrong debug response body: %s", w.Body)
+	}
+
+	// Panics out of the method are recovered too.
+	s = NewServer()
+	s.RegisterService(new(Service1), "")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+	s.Use(func(ctx context.Context, call *CallInfo, next Handler) (int, error, interface{}) {
+		panic("interceptor")
+	})
+	recovered = nil
+	s.SetPanicHandler(func(call *CallInfo, v interface{}, stack []byte) {
+		recovered = v
+	})
+	w = NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != E_INTERNAL || w.Body != "rpc: internal error" || recovered != "interceptor" {
+		t.Errorf("Wrong interceptor panic response: %d %s, %v", w.Status, w.Body, recovered)
+	}
+
+	s = NewServer()
+	s.RegisterService(new(Service1), "")
+	s.RegisterCodec(PanicCodec{}, "mock")
+	w = NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != 500 || w.Body != "rpc: internal error" {
+		t.Errorf("Wrong codec panic response: %d %s", w.Status, w.Body)
+	}
+}
+
+// PanicCodec panics when writing the response.
+type PanicCodec struct {
+	MockCodecRequest
+}
+
+func (c PanicCodec) NewRequest(*http.Request) CodecRequest {
+	return c
+}
+
+func (c PanicCodec) WriteResponse(w http.ResponseWriter, reply interface{}) {
+	panic("codec")
+}
+
+type ServiceSlow struct {
+}
+
+func (t *ServiceSlow) Multiply(ctx context.Context, req *Service1Request, res *Service1Response) error {
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+	return nil
+}
+
+func TestServeHTTPTimeout(t *testing.T) {
+	s := NewServer()
+	err := s.RegisterServiceWithOptions(new(ServiceSlow), "Service1",
+		WithMethodTimeout("Multiply", 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != E_TIMEOUT {
+		t.Errorf("Error code was %d, should be %d.", w.Status, E_TIMEOUT)
+	}
+
+	// A shorter client timeout wins over the method one.
+	s = NewServer()
+	s.RegisterService(new(ServiceSlow), "Service1")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+	s.SetTimeout(time.Minute)
+	r.Header.Set(TimeoutHeader, "10")
+	start := time.Now()
+	w = NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != E_TIMEOUT {
+		t.Errorf("Error code was %d, should be %d.", w.Status, E_TIMEOUT)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Call took %v, client timeout was not honored.", elapsed)
+	}
+
+	// Unknown methods are rejected at registration.
+	err = s.RegisterServiceWithOptions(new(ServiceSlow), "Slow",
+		WithMethodTimeout("Divide", time.Second))
+	if err == nil {
+		t.Errorf("Expected error on unknown method.")
+	}
+}
+
+type ServiceSlowWriter struct {
+	release chan struct{}
+	done    chan struct{}
+}
+
+func (t *ServiceSlowWriter) Multiply(r *http.Request, w http.ResponseWriter, req *Service1Request, res *Service1Response) error {
+	<-t.release
+	w.Header().Set("X-Multiply", "done")
+	res.Result = req.A * req.B
+	close(t.done)
+	return nil
+}
+
+func TestServeHTTPTimeoutWriter(t *testing.T) {
+	s := NewServer()
+	service := &ServiceSlowWriter{make(chan struct{}), make(chan struct{})}
+	s.RegisterService(service, "Service1")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+	s.SetTimeout(10 * time.Millisecond)
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Status != E_TIMEOUT {
+		t.Errorf("Error code was %d, should be %d.", w.Status, E_TIMEOUT)
+	}
+	// The method writes after the response was sent.
+	close(service.release)
+	<-service.done
+	if w.Header().Get("X-Multiply") != "" {
+		t.Errorf("Expected the late write to be discarded.")
+	}
+
+	// In time, the writes reach the response.
+	service = &ServiceSlowWriter{make(chan struct{}), make(chan struct{})}
+	close(service.release)
+	s = NewServer()
+	s.RegisterService(service, "Service1")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+	w = NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Body != "6" || w.Header().Get("X-Multiply") != "done" {
+		t.Errorf("Wrong response: %s %v", w.Body, w.Header())
+	}
+}
+
+type ServiceBlock struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *ServiceBlock) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	t.started <- struct{}{}
+	<-t.release
+	res.Result = req.A * req.B
+	return nil
+}
+
+func TestServeHTTPMaxInFlight(t *testing.T) {
+	s := NewServer()
+	service := &ServiceBlock{make(chan struct{}), make(chan struct{})}
+	err := s.RegisterServiceWithOptions(service, "Service1",
+		WithMethodMaxInFlight("Multiply", 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	newRequest := func() *http.Request {
+		r, err := http.NewRequest("POST", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", "mock")
+		return r
+	}
+	done := make(chan *MockResponseWriter)
+	go func() {
+		w := NewMockResponseWriter()
+		s.ServeHTTP(w, newRequest())
+		done <- w
+	}()
+	<-service.started
+
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, newRequest())
+	if w.Status != http.StatusServiceUnavailable {
+		t.Errorf("Status was %d, should be 503.", w.Status)
+	}
+	stats := s.Stats()
+	if len(stats) != 1 || stats[0].InFlight != 1 || stats[0].Rejected != 1 || stats[0].Calls != 2 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+
+	close(service.release)
+	if w := <-done; w.Body != "6" {
+		t.Errorf("Response body was %s, should be 6.", w.Body)
+	}
+	if stats := s.Stats(); stats[0].InFlight != 0 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+}
+
+func TestServeHTTPLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer()
+	s.SetLogger(NewStdLogger(log.New(&buf, "", 0)))
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	r.RemoteAddr = "1.2.3.4:5678"
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	expected := `level=warn msg=errGet method=Service1.Multiply codec=mock id=<nil> remote=1.2.3.4:5678 err="rpc: can't find service \"Service1.Multiply\""` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Logged %q, should be %q.", buf.String(), expected)
+	}
+}
+
+type Service1Double struct {
+}
+
+func (t *Service1Double) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = 2 * req.A * req.B
+	return nil
+}
+
+func TestReplaceService(t *testing.T) {
+	s := NewServer()
+	s.RegisterService(new(Service1), "")
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	serve := func() *MockResponseWriter {
+		r, err := http.NewRequest("POST", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", "mock")
+		w := NewMockResponseWriter()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	if err := s.ReplaceService(new(Service1Double), "Service1"); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(); w.Body != "12" {
+		t.Errorf("Response body was %s, should be 12.", w.Body)
+	}
+	if err := s.ReplaceService(new(Service1Double), "Foo"); err == nil {
+		t.Errorf("Expected error on replacing an unknown service.")
+	}
+
+	if err := s.UnregisterService("Service1"); err != nil {
+		t.Fatal(err)
+	}
+	if s.HasMethod("Service1.Multiply") || len(s.EnumMethod()) != 0 {
+		t.Errorf("Expected Service1.Multiply to be unregistered.")
+	}
+	if w := serve(); w.Status != 400 {
+		t.Errorf("Status was %d, should be 400.", w.Status)
+	}
+	if err := s.UnregisterService("Service1"); err == nil {
+		t.Errorf("Expected error on unregistering an unknown service.")
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	s := NewServer()
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	factor := 3
+	err := s.RegisterFunc("Service1.Multiply", func(ctx context.Context, req *Service1Request, res *Service1Response) error {
+		res.Result = factor * req.A * req.B
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterFunc("Service1.Add", func(req *Service1Request, res *Service1Response) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if methods := s.EnumMethod(); len(methods) != 2 || !s.HasMethod("Service1.Add") {
+		t.Errorf("Wrong methods: %v", methods)
+	}
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Body != "18" {
+		t.Errorf("Response body was %s, should be 18.", w.Body)
+	}
+
+	// Bad signatures and names.
+	if err := s.RegisterFunc("Service1.Bad", func(a, b int) error { return nil }); err == nil {
+		t.Errorf("Expected error on bad signature.")
+	}
+	if err := s.RegisterFunc("Multiply", func(req *Service1Request, res *Service1Response) error { return nil }); err == nil {
+		t.Errorf("Expected error on ill-formed name.")
+	}
+	if err := s.RegisterFunc("Service1.Multiply", func(req *Service1Request, res *Service1Response) error { return nil }); err == nil {
+		t.Errorf("Expected error on duplicate method.")
+	}
+	s.RegisterService(new(Service1), "Foo")
+	if err := s.RegisterFunc("Foo.Add", func(req *Service1Request, res *Service1Response) error { return nil }); err == nil {
+		t.Errorf("Expected error on adding a func to a receiver service.")
+	}
+}
+
+func TestRegisterServiceWithOptions(t *testing.T) {
+	s := NewServer()
+	err := s.RegisterServiceWithOptions(new(ServiceContext), "Service1",
+		WithMethodName("Multiply", "Times"),
+		WithMethodAlias("Multiply", "Multiply"),
+		WithDeprecatedMethod("Multiply", "use Service1.Times"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.RegisterServiceWithOptions(new(Service1), "Hidden", WithHiddenMethod("Multiply"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RegisterCodec(MockCodec{2, 3}, "mock")
+
+	if !s.HasMethod("Service1.Times") || !s.HasMethod("Service1.Multiply") || !s.HasMethod("Hidden.Multiply") {
+		t.Errorf("Expected methods to be registered.")
+	}
+	if methods := s.EnumMethod(); len(methods) != 1 || methods[0] != "Service1.Times" {
+		t.Errorf("Wrong methods: %v", methods)
+	}
+
+	r, err := http.NewRequest("POST", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "mock")
+	w := NewMockResponseWriter()
+	s.ServeHTTP(w, r)
+	if w.Body != "6" {
+		t.Errorf("Response body was %s, should be 6.", w.Body)
+	}
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Warning") != `299 - "use Service1.Times"` {
+		t.Errorf("Wrong deprecation headers: %v", w.Header())
+	}
+
+	err = s.RegisterServiceWithOptions(new(Service1), "Foo", WithMethodAlias("Multiply", "Multiply"))
+	if err == nil {
+		t.Errorf("Expected error on alias clashing with a method.")
+	}
+}
+
+func TestNameMapper(t *testing.T) {
+	for name, expected := range map[string]string{
+		"GetUser":    "get_user",
+		"GetUserID":  "get_user_id",
+		"HTTPStatus": "http_status",
+		"Service1":   "service1",
+	} {
+		if got := SnakeCase(name); got != expected {
+			t.Errorf("SnakeCase(%q) was %q, should be %q.", name, got, expected)
+		}
+	}
+	for name, expected := range map[string]string{
+		"GetUser":    "getUser",
+		"ID":         "id",
+		"HTTPStatus": "httpStatus",
+	} {
+		if got := CamelCase(name); got != expected {
+			t.Errorf("CamelCase(%q) was %q, should be %q.", name, got, expected)
+		}
+	}
+
+	s := NewServer()
+	s.SetNameMapper(NewNameMapper("_", SnakeCase))
+	s.RegisterService(new(Service1), "")
+	s.RegisterService(new(Service1), "my_service")
+	if !s.HasMethod("service1_multiply") || !s.HasMethod("my_service_multiply") {
+		t.Errorf("Expected methods to be registered: %v", s.EnumMethod())
+	}
+	if s.HasMethod("Service1.Multiply") {
+		t.Errorf("Expected Go names not to be registered.")
+	}
+	// Errors name the methods as the clients call them.
+	err := s.RegisterServiceWithOptions(new(Service1), "other", WithMethodAlias("Multiply", "multiply"))
+	if err == nil || !strings.Contains(err.Error(), `"other_multiply"`) {
+		t.Errorf("Expected error on other_multiply, got %v.", err)
+	}
+
+	// Unqualified names must match a single service.
+	s = NewServer()
+	s.RegisterService(new(Service1), "")
+	if !s.HasMethod("Multiply") {
+		t.Errorf("Expected unqualified method to be found.")
+	}
+	s.RegisterService(new(Service1), "Foo")
+	if _, _, err := s.services.get("Multiply"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected ambiguous method error, got %v.", err)
+	}
+}
+
+type ServiceParams struct {
+}
+
+func (t *ServiceParams) Add(a, b int, res *int) error {
+	*res = a + b
+	return nil
+}
+
+func TestPositionalParams(t *testing.T) {
+	s := NewServer()
+	err := s.RegisterServiceWithOptions(new(ServiceParams), "", WithMethodParams("Add", "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, methodSpec, err := s.services.get("ServiceParams.Add")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := reflect.New(methodSpec.argsType)
+	args.Elem().Field(0).SetInt(2)
+	args.Elem().Field(1).SetInt(3)
+	var res int
+	call := &CallInfo{Method: "ServiceParams.Add", Args: args.Interface(), Reply: &res}
+	if _, err, _ := s.callMethod(context.Background(), methodSpec, nil, call); err != nil || res != 5 {
+		t.Errorf("Expected 5, got %d, %v", res, err)
+	}
+	if tag := methodSpec.argsType.Field(1).Tag.Get("json"); tag != "b" {
+		t.Errorf("Wrong parameter name: %q", tag)
+	}
+
+	if err := s.ReplaceService(new(ServiceParams), "", WithMethodParams("Add", "a")); err == nil {
+		t.Errorf("Expected error on wrong number of names.")
+	}
+	if err := s.RegisterServiceWithOptions(new(Service1), "", WithMethodParams("Multiply", "a")); err == nil {
+		t.Errorf("Expected error on a method without positional parameters.")
+	}
+}
+
+type ServiceHelper struct {
+}
+
+func (t *ServiceHelper) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A * req.B
+	return nil
+}
+
+func (t *ServiceHelper) Helper(name string, res *Service1Response) error {
+	return nil
+}
+
+func (t *ServiceHelper) Pair(a *Service1Request, b *Service1Request, res *Service1Response) error {
+	return nil
+}
+
+func TestPositionalParamsOptIn(t *testing.T) {
+	// Without the option, the methods taking parameters by position are
+	// ignored as before.
+	s := NewServer()
+	if err := s.RegisterService(new(ServiceHelper), ""); err != nil {
+		t.Fatal(err)
+	}
+	if methods := s.EnumMethod(); len(methods) != 1 || methods[0] != "ServiceHelper.Multiply" {
+		t.Errorf("Wrong methods: %v", methods)
+	}
+	if err := s.RegisterService(new(ServiceParams), ""); err == nil {
+		t.Errorf("Expected error on a service without suitable methods.")
+	}
+	if err := s.RegisterServiceWithOptions(new(ServiceHelper), "Timed", WithMethodTimeout("Helper", time.Second)); err == nil {
+		t.Errorf("Expected error on an option of an ignored method.")
+	}
+
+	s = NewServer()
+	if err := s.RegisterServiceWithOptions(new(ServiceHelper), "", WithPositionalParams()); err != nil {
+		t.Fatal(err)
+	}
+	if methods := s.EnumMethod(); len(methods) != 3 {
+		t.Errorf("Wrong methods: %v", methods)
+	}
+	if err := s.RegisterServiceWithOptions(new(ServiceHelper), "Named", WithMethodParams("Helper", "name")); err != nil {
+		t.Fatal(err)
+	}
+	if !s.HasMethod("Named.Helper") || s.HasMethod("Named.Pair") {
+		t.Errorf("Expected only Named.Helper to be registered: %v", s.EnumMethod())
+	}
+}
+
+type ServiceTreeNode struct {
+	Name     string             `json:"name"`
+	Children []*ServiceTreeNode `json:"children,omitempty"`
+	Created  time.Time          `json:"created"`
+}
+
+type ServiceTree struct {
+}
+
+func (t *ServiceTree) Find(ctx context.Context, req *ServiceTreeNode, res *[]ServiceTreeNode) error {
+	return nil
+}
+
+func TestDiscover(t *testing.T) {
+	s := NewServer()
+	s.SetDiscoverInfo("test", "1.0.0")
+	if err := s.RegisterServiceWithOptions(new(Service1), "", WithDeprecatedMethod("Multiply", "use Mul")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterServiceWithOptions(new(Service1), "Secret", WithHiddenMethod("Multiply")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterServiceWithOptions(new(ServiceTree), "", WithMethodTimeout("Find", time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterServiceWithOptions(new(ServiceParams), "", WithPositionalParams()); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := s.Discover()
+	if doc.OpenRPC != OpenRPCVersion || doc.Info.Title != "test" || doc.Info.Version != "1.0.0" {
+		t.Errorf("Wrong document header: %+v", doc)
+	}
+	methods := make(map[string]OpenRPCMethod)
+	for _, method := range doc.Methods {
+		methods[method.Name] = method
+	}
+	if _, ok := methods["Secret.Multiply"]; ok {
+		t.Errorf("Expected hidden method to be left out")
+	}
+
+	multiply := methods["Service1.Multiply"]
+	if !multiply.Deprecated || len(multiply.Params) != 2 || multiply.Params[0].Name != "A" ||
+		multiply.Params[0].Schema["type"] != "integer" || multiply.ParamStructure != "by-name" {
+		t.Errorf("Wrong Service1.Multiply: %+v", multiply)
+	}
+	if ref := multiply.Result.Schema["$ref"]; ref != "#/components/schemas/Service1Response" {
+		t.Errorf("Wrong Service1.Multiply result: %v", multiply.Result.Schema)
+	}
+
+	add := methods["ServiceParams.Add"]
+	if len(add.Params) != 2 || add.Params[1].Name != "p1" || !add.Params[1].Required || add.ParamStructure != "either" {
+		t.Errorf("Wrong ServiceParams.Add params: %+v", add.Params)
+	}
+
+	find := methods["ServiceTree.Find"]
+	hasTimeout := false
+	for _, e := range find.Errors {
+		hasTimeout = hasTimeout || e.Code == E_TIMEOUT
+	}
+	if !hasTimeout {
+		t.Errorf("Expected ServiceTree.Find to report E_TIMEOUT: %+v", find.Errors)
+	}
+	node := doc.Components.Schemas["ServiceTreeNode"]
+	properties, _ := node["properties"].(map[string]interface{})
+	if len(properties) != 3 || properties["created"].(JSONSchema)["format"] != "date-time" {
+		t.Errorf("Wrong ServiceTreeNode schema: %v", node)
+	}
+	children := properties["children"].(JSONSchema)["items"].(JSONSchema)
+	if children["$ref"] != "#/components/schemas/ServiceTreeNode" {
+		t.Errorf("Wrong recursive schema: %v", children)
+	}
+	if result := find.Result.Schema; result["type"] != "array" {
+		t.Errorf("Wrong ServiceTree.Find result: %v", result)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TimeoutHeader is the request header carrying the time, in milliseconds,
+// the client is willing to wait for the call. The server honors it when it
+// is shorter than the timeout of the method.
+const TimeoutHeader = "X-Rpc-Timeout"
+
+// SetTimeoutHeader sets the TimeoutHeader of h from the deadline of ctx. The
+// header is left unset if ctx has no deadline.
+func SetTimeoutHeader(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	h.Set(TimeoutHeader, strconv.FormatInt(ms, 10))
+}
+
+// timeoutFromHeader returns the timeout sent by the client, or zero if the
+// header is missing or invalid.
+func timeoutFromHeader(r *http.Request) time.Duration {
+	ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// callTimeout returns the timeout of a call: the method override, else the
+// service default, else the server default, shortened by the client timeout.
+func (s *Server) callTimeout(r *http.Request, serviceSpec *service, methodSpec *serviceMethod) time.Duration {
+	timeout := methodSpec.timeout
+	if timeout == 0 {
+		timeout = serviceSpec.timeout
+	}
+	if timeout == 0 {
+		timeout = s.timeout
+	}
+	if client := timeoutFromHeader(r); client > 0 && (timeout == 0 || client < timeout) {
+		timeout = client
+	}
+	return timeout
+}
+
+// withDeadline wraps next so that it returns an E_TIMEOUT error as soon as
+// the context deadline expires, without waiting for the method to finish.
+func withDeadline(next Handler) Handler {
+	return func(ctx context.Context, call *CallInfo) (int, error, interface{}) {
+		type result struct {
+			code int
+			err  error
+			data interface{}
+		}
+		done := make(chan result, 1)
+		go func() {
+			code, err, data := next(ctx, call)
+			done <- result{code, err, data}
+		}()
+		select {
+		case res := <-done:
+			return res.code, res.err, res.data
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return E_TIMEOUT, fmt.Errorf("rpc: %s timed out", call.Method), nil
+			}
+			return E_SERVER, fmt.Errorf("rpc: %s canceled", call.Method), nil
+		}
+	}
+}
+
+// deadlineWriter is the http.ResponseWriter of a method running with a
+// timeout. The method may outlive the response, so it writes to a buffer
+// copied to the response only if the method returned in time.
+type deadlineWriter struct {
+	*responseBuffer
+	mutex    sync.Mutex
+	returned bool
+}
+
+func newDeadlineWriter() *deadlineWriter {
+	return &deadlineWriter{responseBuffer: newResponseBuffer()}
+}
+
+// markReturned records that the method returned and no longer writes.
+func (d *deadlineWriter) markReturned() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.returned = true
+}
+
+// flush copies the buffer to w if the method returned, or discards it for
+// good.
+func (d *deadlineWriter) flush(w http.ResponseWriter) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !d.returned {
+		return
+	}
+	for key, values := range d.header {
+		w.Header()[key] = values
+	}
+	if d.status != 0 {
+		w.WriteHeader(d.status)
+	}
+	if d.body.Len() > 0 {
+		w.Write(d.body.Bytes())
+	}
+}
+
+// responseBuffer is a http.ResponseWriter keeping the response in memory.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+// reset drops the response written so far.
+func (b *responseBuffer) reset() {
+	b.header = make(http.Header)
+	b.status = 0
+	b.body.Reset()
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
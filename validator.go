@@ -0,0 +1,100 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Copyright 2012 The Gorilla Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcHttp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MethodValidator is implemented by a Codec that only supports some args and
+// reply types, e.g. protocol buffers messages.
+//
+// A service registered after the codecs of the server fails to register if
+// none of them supports one of its methods, e.g. a service of JSON types on a
+// server with the protorpc codec only. On a server with other codecs, the
+// calls coming through such a codec to a method it does not support are
+// answered with the error of ValidateMethod, and the WithMethodValidator
+// option checks the methods of a service at registration instead.
+type MethodValidator interface {
+	// ValidateMethod checks the args and reply types of a method, as in
+	// "Service.Method". The types are not pointers.
+	ValidateMethod(method string, argsType, replyType reflect.Type) error
+}
+
+// WithMethodValidator checks the methods of the service with validator, e.g.
+// a codec implementing MethodValidator, so that the registration fails if one
+// of them is not supported.
+func WithMethodValidator(validator MethodValidator) ServiceOption {
+	return func(s *service) error {
+		s.validators = append(s.validators, validator)
+		return nil
+	}
+}
+
+// codecValidator accepts the methods supported by one of its validators.
+type codecValidator []MethodValidator
+
+func (v codecValidator) ValidateMethod(method string, argsType, replyType reflect.Type) error {
+	var err error
+	for _, validator := range v {
+		if err = validator.ValidateMethod(method, argsType, replyType); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// newCodecValidator returns the validator of the methods supported by the
+// codecs, in the order of their content types, or nil if one of them does not
+// implement MethodValidator.
+func newCodecValidator(codecs map[string]Codec) MethodValidator {
+	contentTypes := make([]string, 0, len(codecs))
+	for contentType := range codecs {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	var v codecValidator
+	for _, contentType := range contentTypes {
+		validator, ok := codecs[contentType].(MethodValidator)
+		if !ok {
+			return nil
+		}
+		v = append(v, validator)
+	}
+	return v
+}
+
+// validateMethod checks a method of the service named name with validator.
+func validateMethod(validator MethodValidator, name string, spec *serviceMethod) error {
+	if err := validator.ValidateMethod(name, spec.argsType, spec.replyType); err != nil {
+		return fmt.Errorf("rpc: method %q not supported: %v", name, err)
+	}
+	return nil
+}
+
+// validate checks the methods of the service with validators, in the order of
+// their names.
+func (s *service) validate(validators []MethodValidator) error {
+	if len(validators) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(s.methods))
+	for key := range s.methods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, validator := range validators {
+		for _, key := range keys {
+			spec := s.methods[key]
+			if err := validateMethod(validator, s.mapper.Join(s.name, spec.name), spec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
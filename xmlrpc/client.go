@@ -0,0 +1,151 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/Limard/rpcHttp"
+)
+
+var ContentType = `text/xml`
+
+// Client calls methods on XML-RPC servers. It is safe for concurrent use.
+// Call and CallContext use a Client with the default options.
+type Client struct {
+	httpClient *http.Client
+	logger     rpcHttp.Logger
+}
+
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client configured by the options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{httpClient: http.DefaultClient, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithHTTPClient sets the http.Client sending the requests,
+// http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// WithClientLogger sets the logger of the client.
+func WithClientLogger(logger rpcHttp.Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+func encodeClientRequest(method string, args interface{}) ([]byte, error) {
+	return encodeMethodCall(method, args)
+}
+
+func decodeClientResponse(r io.Reader, reply interface{}) (e error) {
+	var c methodResponse
+	if err := xml.NewDecoder(r).Decode(&c); err != nil {
+		return &Error{
+			Code:    E_PARSE,
+			Message: err.Error()}
+	}
+
+	// Fault
+	if c.Fault != nil {
+		var f fault
+		if err := c.Fault.decode(reflect.ValueOf(&f).Elem()); err != nil {
+			return &Error{
+				Code:    E_PARSE,
+				Message: err.Error(),
+			}
+		}
+		return &Error{
+			Code:    f.Code,
+			Message: f.Message,
+		}
+	}
+
+	// Result
+	if len(c.Params) == 0 {
+		return &Error{
+			Code:    E_BAD_PARAMS,
+			Message: "result is null",
+		}
+	}
+	if err := c.Params[0].decode(reflect.ValueOf(reply).Elem()); err != nil {
+		return &Error{
+			Code:    E_PARSE,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func Call(url string, method string, request interface{}, reply interface{}) (e error) {
+	return CallContext(context.Background(), http.DefaultClient, url, method, request, reply)
+}
+
+// CallContext calls method with client. The deadline of ctx, if any, is sent
+// to the server in the rpcHttp.TimeoutHeader.
+func CallContext(ctx context.Context, client *http.Client, url string, method string, request interface{}, reply interface{}) (e error) {
+	return NewClient(WithHTTPClient(client)).CallContext(ctx, url, method, request, reply)
+}
+
+// Call calls method on the server at url, decoding the result into reply.
+func (c *Client) Call(url string, method string, request interface{}, reply interface{}) error {
+	return c.CallContext(context.Background(), url, method, request, reply)
+}
+
+// CallContext calls method like Call. The deadline of ctx, if any, is sent to
+// the server in the rpcHttp.TimeoutHeader.
+func (c *Client) CallContext(ctx context.Context, url string, method string, request interface{}, reply interface{}) error {
+	fields := []interface{}{"method", method, "codec", ContentType, "remote", url}
+	reqBuf, err := encodeClientRequest(method, request)
+	if err != nil {
+		c.logger.Error("encodeClientRequest", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBuf))
+	if err != nil {
+		return &Error{
+			Code:    E_INVALID_REQ,
+			Message: err.Error()}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	rpcHttp.SetTimeoutHeader(ctx, req.Header)
+
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("http.Post", append(fields, "err", err)...)
+		return &Error{
+			Code:    E_SERVER,
+			Message: err.Error()}
+	}
+	defer rsp.Body.Close()
+
+	err = decodeClientResponse(rsp.Body, reply)
+	if err != nil {
+		// The faults of the server are returned as is.
+		if replyErr, ok := err.(*Error); !ok || replyErr.Code == E_PARSE {
+			c.logger.Warn("decodeClientResponse", append(fields, "err", err)...)
+		}
+	}
+	return err
+}
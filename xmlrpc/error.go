@@ -0,0 +1,26 @@
+package xmlrpc
+
+import "encoding/json"
+
+const (
+	E_PARSE       = -32700
+	E_INVALID_REQ = -32600
+	E_NO_METHOD   = -32601
+	E_BAD_PARAMS  = -32602
+	E_INTERNAL    = -32603
+	E_SERVER      = -32000
+	E_TIMEOUT     = -32001
+	E_BUSY        = -32002
+)
+
+// Error is an XML-RPC fault. Code and Message are sent as the faultCode and
+// faultString members of the fault.
+type Error struct {
+	Code    int    `json:"code"`    /* required */
+	Message string `json:"message"` /* required */
+}
+
+func (e *Error) Error() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
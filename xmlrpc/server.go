@@ -0,0 +1,130 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/Limard/rpcHttp"
+)
+
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{encSel: rpcHttp.DefaultEncoderSelector, logger: rpcHttp.NopLogger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithLogger sets the logger of the codec.
+func WithLogger(logger rpcHttp.Logger) CodecOption {
+	return func(c *Codec) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// Codec creates a CodecRequest to process each request.
+type Codec struct {
+	encSel rpcHttp.EncoderSelector
+	logger rpcHttp.Logger
+}
+
+func (c *Codec) NewRequest(r *http.Request) rpcHttp.CodecRequest {
+	req := new(methodCall)
+	var err error
+	if e := xml.NewDecoder(r.Body).Decode(req); e != nil {
+		err = &Error{
+			Code:    E_PARSE,
+			Message: e.Error(),
+		}
+	} else if req.Method == "" {
+		err = &Error{
+			Code:    E_INVALID_REQ,
+			Message: "xmlrpc: missing methodName",
+		}
+	}
+	r.Body.Close()
+	return &CodecRequest{
+		request: req,
+		err:     err,
+		encoder: c.encSel.Select(r),
+		logger:  c.logger,
+		remote:  r.RemoteAddr,
+	}
+}
+
+type CodecRequest struct {
+	request *methodCall
+	err     error
+	encoder rpcHttp.Encoder
+	logger  rpcHttp.Logger
+	remote  string
+}
+
+func (c *CodecRequest) Method() (string, error) {
+	if c.err == nil {
+		return c.request.Method, nil
+	}
+	return "", c.err
+}
+
+// ReadRequest decodes the params into args. A single struct param is read
+// as the whole args, several params fill the fields of args by position.
+func (c *CodecRequest) ReadRequest(args interface{}) error {
+	if c.err == nil {
+		if err := readParams(c.request.Params, args); err != nil {
+			c.logger.Warn("invalid params", c.logFields("err", err)...)
+			c.err = &Error{
+				Code:    E_BAD_PARAMS,
+				Message: err.Error(),
+			}
+		}
+	}
+	return c.err
+}
+
+// WriteResponse encodes the response and writes it to the ResponseWriter.
+func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}) {
+	buffer, err := encodeMethodResponse(reply)
+	if err != nil {
+		c.logger.Error("xml Encode", c.logFields("err", err)...)
+		buffer = encodeFault(&Error{
+			Code:    E_INTERNAL,
+			Message: err.Error(),
+		})
+	}
+	c.writeServerResponse(w, buffer)
+}
+
+// WriteErrorResponse writes err as a fault. The faultCode is the code of an
+// *Error, or code otherwise.
+func (c *CodecRequest) WriteErrorResponse(w http.ResponseWriter, code int, err error, data interface{}) {
+	objErr, ok := err.(*Error)
+	if !ok {
+		objErr = &Error{
+			Code:    code,
+			Message: err.Error(),
+		}
+	}
+	c.writeServerResponse(w, encodeFault(objErr))
+}
+
+func (c *CodecRequest) writeServerResponse(w http.ResponseWriter, buffer []byte) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	if err := rpcHttp.EncodeResponse(w, c.encoder, buffer); err != nil {
+		c.logger.Warn("write response", c.logFields("err", err)...)
+	}
+}
+
+// logFields returns the log fields identifying the request followed by
+// keyvals.
+func (c *CodecRequest) logFields(keyvals ...interface{}) []interface{} {
+	fields := []interface{}{"method", c.request.Method, "codec", ContentType, "remote", c.remote}
+	return append(fields, keyvals...)
+}
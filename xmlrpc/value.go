@@ -0,0 +1,596 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iso8601 is the layout of the dateTime.iso8601 values.
+const iso8601 = "20060102T15:04:05"
+
+// dateTimeLayouts are the layouts accepted when decoding dateTime.iso8601
+// values, as sent by the various XML-RPC implementations.
+var dateTimeLayouts = []string{
+	iso8601,
+	"2006-01-02T15:04:05",
+	"20060102T15:04:05Z07:00",
+	time.RFC3339,
+}
+
+var (
+	typeOfTime  = reflect.TypeOf(time.Time{})
+	typeOfBytes = reflect.TypeOf([]byte(nil))
+)
+
+// ----------------------------------------------------------------------------
+// Documents
+// ----------------------------------------------------------------------------
+
+// methodCall is an XML-RPC request.
+type methodCall struct {
+	XMLName xml.Name `xml:"methodCall"`
+	Method  string   `xml:"methodName"`
+	Params  []value  `xml:"params>param>value"`
+}
+
+// methodResponse is an XML-RPC response, holding either a param or a fault.
+type methodResponse struct {
+	XMLName xml.Name `xml:"methodResponse"`
+	Params  []value  `xml:"params>param>value"`
+	Fault   *value   `xml:"fault>value"`
+}
+
+// fault is the struct value of a fault.
+type fault struct {
+	Code    int    `xmlrpc:"faultCode"`
+	Message string `xmlrpc:"faultString"`
+}
+
+// value is a parsed <value> element. A value without a type element is a
+// string.
+type value struct {
+	Text     string       `xml:",chardata"`
+	Int      *string      `xml:"int"`
+	I4       *string      `xml:"i4"`
+	I8       *string      `xml:"i8"`
+	Boolean  *string      `xml:"boolean"`
+	String   *string      `xml:"string"`
+	Double   *string      `xml:"double"`
+	DateTime *string      `xml:"dateTime.iso8601"`
+	Base64   *string      `xml:"base64"`
+	Struct   *structValue `xml:"struct"`
+	Array    *arrayValue  `xml:"array"`
+	Nil      *struct{}    `xml:"nil"`
+}
+
+type structValue struct {
+	Members []member `xml:"member"`
+}
+
+type member struct {
+	Name  string `xml:"name"`
+	Value value  `xml:"value"`
+}
+
+type arrayValue struct {
+	Values []value `xml:"data>value"`
+}
+
+// ----------------------------------------------------------------------------
+// Encoding
+// ----------------------------------------------------------------------------
+
+// encodeMethodCall returns the methodCall document of method, with args as
+// the single param. A nil args is sent without params.
+func encodeMethodCall(method string, args interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header + "<methodCall><methodName>")
+	xml.EscapeText(&buffer, []byte(method))
+	buffer.WriteString("</methodName><params>")
+	if args != nil {
+		buffer.WriteString("<param>")
+		if err := encodeValue(&buffer, reflect.ValueOf(args)); err != nil {
+			return nil, err
+		}
+		buffer.WriteString("</param>")
+	}
+	buffer.WriteString("</params></methodCall>")
+	return buffer.Bytes(), nil
+}
+
+// encodeMethodResponse returns the methodResponse document holding reply.
+func encodeMethodResponse(reply interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header + "<methodResponse><params><param>")
+	if err := encodeValue(&buffer, reflect.ValueOf(reply)); err != nil {
+		return nil, err
+	}
+	buffer.WriteString("</param></params></methodResponse>")
+	return buffer.Bytes(), nil
+}
+
+// encodeFault returns the methodResponse document holding the fault of err.
+func encodeFault(err *Error) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header + "<methodResponse><fault>")
+	// A fault struct holds an int and a string, it cannot fail.
+	encodeValue(&buffer, reflect.ValueOf(fault{Code: err.Code, Message: err.Message}))
+	buffer.WriteString("</fault></methodResponse>")
+	return buffer.Bytes()
+}
+
+// encodeValue writes v as a <value> element.
+func encodeValue(buffer *bytes.Buffer, v reflect.Value) error {
+	buffer.WriteString("<value>")
+	if err := encodeData(buffer, v); err != nil {
+		return err
+	}
+	buffer.WriteString("</value>")
+	return nil
+}
+
+// encodeData writes the typed element of v. Nil pointers and interfaces are
+// written as the <nil/> extension.
+func encodeData(buffer *bytes.Buffer, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			buffer.WriteString("<nil/>")
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		buffer.WriteString("<nil/>")
+		return nil
+	}
+	if v.Type() == typeOfTime {
+		buffer.WriteString("<dateTime.iso8601>" + v.Interface().(time.Time).UTC().Format(iso8601) + "</dateTime.iso8601>")
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buffer.WriteString("<boolean>1</boolean>")
+		} else {
+			buffer.WriteString("<boolean>0</boolean>")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeInt(buffer, v.Int(), strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return fmt.Errorf("xmlrpc: %d overflows i8", v.Uint())
+		}
+		writeInt(buffer, int64(v.Uint()), strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		// XML-RPC doubles have no exponent.
+		buffer.WriteString("<double>" + strconv.FormatFloat(v.Float(), 'f', -1, 64) + "</double>")
+	case reflect.String:
+		buffer.WriteString("<string>")
+		xml.EscapeText(buffer, []byte(v.String()))
+		buffer.WriteString("</string>")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			buffer.WriteString("<base64>" + base64.StdEncoding.EncodeToString(v.Bytes()) + "</base64>")
+			return nil
+		}
+		buffer.WriteString("<array><data>")
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buffer, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("</data></array>")
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("xmlrpc: unsupported map key type %s", v.Type().Key())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		buffer.WriteString("<struct>")
+		for _, key := range keys {
+			mapKey := reflect.ValueOf(key).Convert(v.Type().Key())
+			if err := encodeMember(buffer, key, v.MapIndex(mapKey)); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("</struct>")
+	case reflect.Struct:
+		buffer.WriteString("<struct>")
+		for _, f := range structFields(v.Type()) {
+			field := v.Field(f.index)
+			if f.omitEmpty && isEmptyValue(field) {
+				continue
+			}
+			if err := encodeMember(buffer, f.name, field); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("</struct>")
+	default:
+		return fmt.Errorf("xmlrpc: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// writeInt writes n as an <int>, or as the <i8> extension if it does not fit
+// in 32 bits.
+func writeInt(buffer *bytes.Buffer, n int64, text string) {
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		buffer.WriteString("<i8>" + text + "</i8>")
+		return
+	}
+	buffer.WriteString("<int>" + text + "</int>")
+}
+
+func encodeMember(buffer *bytes.Buffer, name string, v reflect.Value) error {
+	buffer.WriteString("<member><name>")
+	xml.EscapeText(buffer, []byte(name))
+	buffer.WriteString("</name>")
+	if err := encodeValue(buffer, v); err != nil {
+		return err
+	}
+	buffer.WriteString("</member>")
+	return nil
+}
+
+// field is a struct field encoded as a struct member.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// structFields returns the fields of struct type t encoded as members: the
+// exported fields, named by their xmlrpc tag if any. A field with the tag
+// "-" is ignored.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("xmlrpc")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{name: name, index: i, omitEmpty: opts == "omitempty"})
+	}
+	return fields
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ----------------------------------------------------------------------------
+// Decoding
+// ----------------------------------------------------------------------------
+
+// readParams decodes the params of a methodCall into args. A single struct
+// param is read as the whole args, otherwise the params fill the fields of
+// the args struct by position, as for a method taking several parameters.
+func readParams(params []value, args interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	v := reflect.ValueOf(args).Elem()
+	if v.Kind() != reflect.Struct || v.Type() == typeOfTime {
+		if len(params) != 1 {
+			return fmt.Errorf("wrong number of params: got %d, want 1", len(params))
+		}
+		return params[0].decode(v)
+	}
+	fields := structFields(v.Type())
+	if len(params) == 1 && params[0].Struct != nil &&
+		(len(fields) != 1 || !acceptsStruct(v.Field(fields[0].index).Type())) {
+		return params[0].decode(v)
+	}
+	if len(params) != len(fields) {
+		return fmt.Errorf("wrong number of params: got %d, want %d", len(params), len(fields))
+	}
+	for i, f := range fields {
+		if err := params[i].decode(v.Field(f.index)); err != nil {
+			return fmt.Errorf("param %d (%s): %v", i, f.name, err)
+		}
+	}
+	return nil
+}
+
+// acceptsStruct reports whether a struct value can be decoded into type t.
+func acceptsStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return t != typeOfTime
+	}
+	return false
+}
+
+// decode stores the value in v, which must be settable.
+func (val *value) decode(v reflect.Value) error {
+	if val.Nil != nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		x, err := val.interfaceValue()
+		if err != nil {
+			return err
+		}
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return nil
+	}
+
+	switch {
+	case val.Struct != nil:
+		return val.Struct.decode(v)
+	case val.Array != nil:
+		return val.Array.decode(v)
+	case val.Base64 != nil:
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(*val.Base64))
+		if err != nil {
+			return err
+		}
+		if v.Kind() == reflect.String {
+			v.SetString(string(b))
+			return nil
+		}
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return typeError("base64", v)
+		}
+		v.SetBytes(b)
+	case val.DateTime != nil:
+		if v.Type() != typeOfTime {
+			return typeError("dateTime.iso8601", v)
+		}
+		t, err := parseDateTime(*val.DateTime)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+	case val.Int != nil || val.I4 != nil || val.I8 != nil:
+		text, name := val.intText()
+		n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(n) {
+				return fmt.Errorf("xmlrpc: %d overflows %s", n, v.Type())
+			}
+			v.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return fmt.Errorf("xmlrpc: %d overflows %s", n, v.Type())
+			}
+			v.SetUint(uint64(n))
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(n))
+		default:
+			return typeError(name, v)
+		}
+	case val.Boolean != nil:
+		b, err := parseBoolean(*val.Boolean)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Bool {
+			return typeError("boolean", v)
+		}
+		v.SetBool(b)
+	case val.Double != nil:
+		f, err := strconv.ParseFloat(strings.TrimSpace(*val.Double), 64)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return typeError("double", v)
+		}
+		v.SetFloat(f)
+	default:
+		if v.Kind() != reflect.String {
+			return typeError("string", v)
+		}
+		v.SetString(val.stringText())
+	}
+	return nil
+}
+
+// interfaceValue returns the value as the Go value stored in an empty
+// interface: int, int64, bool, float64, string, time.Time, []byte,
+// map[string]interface{} or []interface{}.
+func (val *value) interfaceValue() (interface{}, error) {
+	var v reflect.Value
+	switch {
+	case val.Nil != nil:
+		return nil, nil
+	case val.Struct != nil:
+		m := map[string]interface{}{}
+		v = reflect.ValueOf(&m).Elem()
+	case val.Array != nil:
+		var a []interface{}
+		v = reflect.ValueOf(&a).Elem()
+	case val.Base64 != nil:
+		v = reflect.New(typeOfBytes).Elem()
+	case val.DateTime != nil:
+		v = reflect.New(typeOfTime).Elem()
+	case val.I8 != nil:
+		v = reflect.New(reflect.TypeOf(int64(0))).Elem()
+	case val.Int != nil || val.I4 != nil:
+		v = reflect.New(reflect.TypeOf(0)).Elem()
+	case val.Boolean != nil:
+		v = reflect.New(reflect.TypeOf(false)).Elem()
+	case val.Double != nil:
+		v = reflect.New(reflect.TypeOf(0.0)).Elem()
+	default:
+		return val.stringText(), nil
+	}
+	if err := val.decode(v); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// intText returns the text and the element name of an integer value.
+func (val *value) intText() (string, string) {
+	switch {
+	case val.Int != nil:
+		return *val.Int, "int"
+	case val.I4 != nil:
+		return *val.I4, "i4"
+	}
+	return *val.I8, "i8"
+}
+
+// stringText returns the text of a string value, typed or not.
+func (val *value) stringText() string {
+	if val.String != nil {
+		return *val.String
+	}
+	return val.Text
+}
+
+// decode stores the members in v, a struct or a map with string keys.
+// Members without a matching field are ignored.
+func (s *structValue) decode(v reflect.Value) error {
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, m := range s.Members {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := m.Value.decode(elem); err != nil {
+				return fmt.Errorf("%s: %v", m.Name, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(m.Name).Convert(v.Type().Key()), elem)
+		}
+	case v.Kind() == reflect.Struct && v.Type() != typeOfTime:
+		fields := structFields(v.Type())
+		for _, m := range s.Members {
+			f, ok := fieldByName(fields, m.Name)
+			if !ok {
+				continue
+			}
+			if err := m.Value.decode(v.Field(f.index)); err != nil {
+				return fmt.Errorf("%s: %v", m.Name, err)
+			}
+		}
+	default:
+		return typeError("struct", v)
+	}
+	return nil
+}
+
+// fieldByName returns the field of the member name, preferring an exact match
+// to a case-insensitive one.
+func fieldByName(fields []field, name string) (field, bool) {
+	var fold *field
+	for i := range fields {
+		if fields[i].name == name {
+			return fields[i], true
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, name) {
+			fold = &fields[i]
+		}
+	}
+	if fold != nil {
+		return *fold, true
+	}
+	return field{}, false
+}
+
+// decode stores the values in v, a slice or an array.
+func (a *arrayValue) decode(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(a.Values), len(a.Values))
+		for i := range a.Values {
+			if err := a.Values[i].decode(slice.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		if len(a.Values) > v.Len() {
+			return fmt.Errorf("xmlrpc: array of %d values overflows %s", len(a.Values), v.Type())
+		}
+		for i := range a.Values {
+			if err := a.Values[i].decode(v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+	default:
+		return typeError("array", v)
+	}
+	return nil
+}
+
+func parseDateTime(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	var err error
+	for _, layout := range dateTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func parseBoolean(text string) (bool, error) {
+	switch strings.TrimSpace(text) {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("xmlrpc: invalid boolean %q", text)
+}
+
+func typeError(xmlType string, v reflect.Value) error {
+	return fmt.Errorf("xmlrpc: cannot decode %s into %s", xmlType, v.Type())
+}
@@ -0,0 +1,193 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Limard/rpcHttp"
+)
+
+var ErrResponseError = errors.New("response error")
+
+type Service1Request struct {
+	A int
+	B int
+}
+
+type Service1Response struct {
+	Result int
+}
+
+type Service1 struct {
+}
+
+func (t *Service1) Multiply(r *http.Request, req *Service1Request, res *Service1Response) error {
+	res.Result = req.A * req.B
+	return nil
+}
+
+func (t *Service1) ResponseError(r *http.Request, req *Service1Request, res *Service1Response) (int, error) {
+	return 4, ErrResponseError
+}
+
+func (t *Service1) Greet(name string, times int, res *string) error {
+	*res = strings.Repeat("hello "+name+" ", times)
+	return nil
+}
+
+type Record struct {
+	Name    string    `xmlrpc:"name"`
+	Count   int64     `xmlrpc:"count"`
+	Ratio   float64   `xmlrpc:"ratio"`
+	Enabled bool      `xmlrpc:"enabled"`
+	Blob    []byte    `xmlrpc:"blob"`
+	When    time.Time `xmlrpc:"when"`
+	Tags    []string  `xmlrpc:"tags"`
+	Extra   *Record   `xmlrpc:"extra,omitempty"`
+	Any     interface{}
+}
+
+func (t *Service1) Echo(r *http.Request, req *Record, res *Record) error {
+	*res = *req
+	return nil
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+func newServer() *rpcHttp.Server {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	s.RegisterServiceWithOptions(new(Service1), "", rpcHttp.WithPositionalParams())
+	return s
+}
+
+func serveRaw(s *rpcHttp.Server, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/", bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", ContentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestMethodCall(t *testing.T) {
+	s := newServer()
+
+	w := serveRaw(s, `<?xml version="1.0"?>
+<methodCall>
+  <methodName>Service1.Multiply</methodName>
+  <params>
+    <param><value><struct>
+      <member><name>A</name><value><i4>4</i4></value></member>
+      <member><name>B</name><value><int>2</int></value></member>
+    </struct></value></param>
+  </params>
+</methodCall>`)
+	want := xmlHeader + `<methodResponse><params><param><value><struct><member><name>Result</name><value><int>8</int></value></member></struct></value></param></params></methodResponse>`
+	if w.Body.String() != want {
+		t.Errorf("Wrong response: got %s, want %s", w.Body.String(), want)
+	}
+
+	// Positional params, the untyped value is a string.
+	w = serveRaw(s, `<methodCall><methodName>Service1.Greet</methodName><params>
+<param><value>go</value></param><param><value><int>2</int></value></param>
+</params></methodCall>`)
+	want = xmlHeader + `<methodResponse><params><param><value><string>hello go hello go </string></value></param></params></methodResponse>`
+	if w.Body.String() != want {
+		t.Errorf("Wrong positional response: got %s, want %s", w.Body.String(), want)
+	}
+
+	w = serveRaw(s, `<methodCall><methodName>Service1.ResponseError</methodName><params/></methodCall>`)
+	want = xmlHeader + `<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member><member><name>faultString</name><value><string>response error</string></value></member></struct></value></fault></methodResponse>`
+	if w.Body.String() != want {
+		t.Errorf("Wrong fault: got %s, want %s", w.Body.String(), want)
+	}
+
+	w = serveRaw(s, `<methodCall><methodName>Service1.Multiply</methodName><params><param><value><boolean>1</boolean></value></param></params></methodCall>`)
+	var res Service1Response
+	if err := decodeClientResponse(w.Body, &res); err == nil || err.(*Error).Code != E_BAD_PARAMS {
+		t.Errorf("Expected a bad params fault, got %v", err)
+	}
+}
+
+func TestMethodParams(t *testing.T) {
+	s := rpcHttp.NewServer()
+	s.RegisterCodec(NewCodec(), ContentType)
+	if err := s.RegisterServiceWithOptions(new(Service1), "", rpcHttp.WithMethodParams("Greet", "name", "times")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A single struct param passes the params by name.
+	w := serveRaw(s, `<methodCall><methodName>Service1.Greet</methodName><params><param><value><struct>
+<member><name>times</name><value><int>2</int></value></member>
+<member><name>name</name><value><string>go</string></value></member>
+</struct></value></param></params></methodCall>`)
+	want := xmlHeader + `<methodResponse><params><param><value><string>hello go hello go </string></value></param></params></methodResponse>`
+	if w.Body.String() != want {
+		t.Errorf("Wrong by-name response: got %s, want %s", w.Body.String(), want)
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(newServer())
+	defer server.Close()
+
+	var res Service1Response
+	if err := Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d, %v", res.Result, err)
+	}
+	err := Call(server.URL, "Service1.ResponseError", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != 4 || replyErr.Message != ErrResponseError.Error() {
+		t.Errorf("Expected fault 4 %q, got %v", ErrResponseError, err)
+	}
+
+	req := &Record{
+		Name:    "a < b & c",
+		Count:   1 << 40,
+		Ratio:   0.25,
+		Enabled: true,
+		Blob:    []byte{0, 1, 2, 255},
+		When:    time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		Tags:    []string{"x", "y"},
+		Extra:   &Record{Name: "inner", Blob: []byte{}, Tags: []string{}, When: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		Any:     map[string]interface{}{"n": 1, "s": "t", "l": []interface{}{true, 1.5}},
+	}
+	var echo Record
+	if err := Call(server.URL, "Service1.Echo", req, &echo); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&echo, req) {
+		t.Errorf("Wrong echo:\ngot  %+v\nwant %+v", echo, *req)
+	}
+
+	// Times are sent in UTC.
+	when := time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("UTC+2", 2*60*60))
+	req = &Record{Blob: []byte{}, When: when, Extra: &Record{Blob: []byte{}, When: when}}
+	if err := Call(server.URL, "Service1.Echo", req, &echo); err != nil || !echo.When.Equal(when) || !echo.Extra.When.Equal(when) {
+		t.Errorf("Wrong time: %v, want %v, %v", echo.When, when, err)
+	}
+}
+
+func TestClientLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("oops"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := NewClient(WithHTTPClient(server.Client()), WithClientLogger(rpcHttp.NewStdLogger(log.New(&buf, "", 0))))
+	var res Service1Response
+	err := client.Call(server.URL, "Service1.Multiply", &Service1Request{4, 2}, &res)
+	if replyErr, ok := err.(*Error); !ok || replyErr.Code != E_PARSE {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "level=warn msg=decodeClientResponse method=Service1.Multiply") {
+		t.Errorf("Wrong log: %q", buf.String())
+	}
+}